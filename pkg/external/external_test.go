@@ -1312,3 +1312,149 @@ func TestProjectFamilyStructure(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractFamiliesDetailed(t *testing.T) {
+	graph := &requests.ProjectMapGetStudentGraphTemplate_Data{
+		HolyGraph: requests.ProjectMapGetStudentGraphTemplate_Data_HolyGraph{
+			GetStudentGraphTemplate: requests.ProjectMapGetStudentGraphTemplate_Data_GetStudentGraphTemplate{
+				Nodes: []requests.ProjectMapGetStudentGraphTemplate_Data_Node{
+					{
+						Label:    "C - I",
+						Position: requests.ProjectMapGetStudentGraphTemplate_Data_Position{X: 10, Y: 20},
+						Items: []requests.ProjectMapGetStudentGraphTemplate_Data_Item{
+							{Goal: &requests.ProjectMapGetStudentGraphTemplate_Data_Course{ProjectID: 1, ProjectName: "C2_SimpleBashUtils", IsMandatory: true}},
+							{},
+							{Course: &requests.ProjectMapGetStudentGraphTemplate_Data_Course{ProjectID: 2, ProjectName: "C_Intensive"}},
+						},
+					},
+					{
+						Label:    "Go - I",
+						Position: requests.ProjectMapGetStudentGraphTemplate_Data_Position{X: 30, Y: 40},
+						Items: []requests.ProjectMapGetStudentGraphTemplate_Data_Item{
+							{
+								Goal:   &requests.ProjectMapGetStudentGraphTemplate_Data_Course{ProjectID: 3, ProjectName: "GO1_Boot"},
+								Course: &requests.ProjectMapGetStudentGraphTemplate_Data_Course{ProjectID: 4, ProjectName: "GO_Course"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	details := ExtractFamiliesDetailed(graph)
+
+	require.Len(t, details, 3)
+	assert.Equal(t, ProjectFamilyDetail{
+		FamilyLabel: "C - I", ProjectName: "C2_SimpleBashUtils", ProjectID: 1, Kind: ProjectKindGoal,
+		IsMandatory: true, NodeIndex: 0, ItemIndex: 0, PositionX: 10, PositionY: 20,
+	}, details[0])
+	assert.Equal(t, "C_Intensive", details[1].ProjectName)
+	assert.Equal(t, ProjectKindCourse, details[1].Kind)
+	assert.Equal(t, 2, details[1].ItemIndex)
+	assert.Equal(t, "GO1_Boot", details[2].ProjectName)
+	assert.Equal(t, ProjectKindGoal, details[2].Kind)
+	assert.Equal(t, 1, details[2].NodeIndex)
+
+	families, err := ExtractFamilies(graph)
+	require.NoError(t, err)
+	require.Len(t, families, len(details))
+	for i, family := range families {
+		assert.Equal(t, family.ProjectName, details[i].ProjectName)
+		assert.Equal(t, family.FamilyLabel, details[i].FamilyLabel)
+	}
+
+	assert.Nil(t, ExtractFamiliesDetailed(nil))
+}
+
+// TestExtractCtxCancelled tests that the ctx-aware Extract* variants stop on a cancelled context
+func TestExtractCtxCancelled(t *testing.T) {
+	baseTime := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	slots := make([]requests.CalendarGetEvents_Data_EventSlot, 1000)
+	bookings := make([]interface{}, 1000)
+	for i := range slots {
+		slots[i] = requests.CalendarGetEvents_Data_EventSlot{ID: "slot", Start: baseTime, End: baseTime.Add(time.Hour)}
+		bookings[i] = map[string]interface{}{"id": "booking"}
+	}
+	events := &requests.CalendarGetEvents_Data{
+		CalendarEventS21: requests.CalendarGetEvents_Data_CalendarEventS21{
+			GetMyCalendarEvents: []requests.CalendarGetEvents_Data_GetMyCalendarEvent{
+				{EventSlots: slots, Bookings: bookings},
+			},
+		},
+	}
+
+	notifications := make([]requests.GetUserNotifications_Data_Notification, 1000)
+	for i := range notifications {
+		notifications[i] = requests.GetUserNotifications_Data_Notification{ID: "notif", Time: baseTime}
+	}
+	notificationData := &requests.GetUserNotifications_Data{
+		S21Notification: requests.GetUserNotifications_Data_S21Notification{
+			GetS21Notifications: requests.GetUserNotifications_Data_GetS21Notifications{
+				Notifications: notifications,
+			},
+		},
+	}
+
+	gotSlots, err := ExtractSlotsCtx(ctx, events)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, gotSlots)
+
+	gotBookings, err := ExtractBookingsCtx(ctx, events)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, gotBookings)
+
+	gotNotifications, err := ExtractNotificationsCtx(ctx, notificationData)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, gotNotifications)
+
+	// The non-ctx variants still process everything
+	assert.Len(t, ExtractSlots(events), 1000)
+	assert.Len(t, ExtractNotifications(notificationData), 1000)
+}
+
+// TestExtractCurrentUser tests the ExtractCurrentUser function
+func TestExtractCurrentUser(t *testing.T) {
+	t.Run("Populated response", func(t *testing.T) {
+		data := &requests.GetCurrentUser_Data{
+			User: requests.GetCurrentUser_Data_User{
+				GetCurrentUser: requests.GetCurrentUser_Data_GetCurrentUser{
+					ID:                     "user-id",
+					Login:                  "reviewer",
+					FirstName:              "Ivan",
+					MiddleName:             "",
+					LastName:               "Petrov",
+					CurrentSchoolStudentID: "student-42",
+				},
+			},
+		}
+
+		user, err := ExtractCurrentUser(data)
+
+		require.NoError(t, err)
+		assert.Equal(t, &models.CurrentUser{
+			ID:              "user-id",
+			Login:           "reviewer",
+			FullName:        "Ivan Petrov",
+			SchoolStudentID: "student-42",
+		}, user)
+	})
+
+	t.Run("Empty response", func(t *testing.T) {
+		user, err := ExtractCurrentUser(&requests.GetCurrentUser_Data{})
+
+		assert.Error(t, err)
+		assert.Nil(t, user)
+	})
+
+	t.Run("Nil response", func(t *testing.T) {
+		user, err := ExtractCurrentUser(nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, user)
+	})
+}
@@ -3,14 +3,24 @@ package external
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	s21client "github.com/arseniisemenow/s21auto-client-go"
 	s21auth "github.com/arseniisemenow/s21auto-client-go/auth"
+	"github.com/arseniisemenow/s21auto-client-go/gql"
 	"github.com/arseniisemenow/s21auto-client-go/requests"
 	"github.com/arseniisemenow/s21auto-client-go/review"
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 )
@@ -18,17 +28,25 @@ import (
 // S21Client wraps the s21auto client with our application logic
 type S21Client struct {
 	client *s21client.Client
+	auth   *S21AuthProvider // nil when the client authenticates with username/password
+	login  string           // known only for username/password clients
+	tracer trace.Tracer     // nil disables tracing
 }
 
 // S21AuthProvider implements authentication using stored access token
 type S21AuthProvider struct {
+	mu             sync.Mutex
 	token          s21auth.Token
+	schoolIDMu     sync.RWMutex // writers of schoolID hold both mu and schoolIDMu, so readers may hold either
 	schoolID       string
 	contextHeaders *s21client.ContextHeaders
 	clientID       string // Configurable client_id for token refresh (default: "school21")
 }
 
-// refreshTokenWithCustomClientID manually refreshes token using configured client_id
+// tokenEndpointURL is the Keycloak endpoint used to refresh access tokens
+var tokenEndpointURL = "https://auth.21-school.ru/auth/realms/EduPowerKeycloak/protocol/openid-connect/token"
+
+// refreshTokenWithCustomClientID manually refreshes token using configured client_id; caller must hold mu
 func (provider *S21AuthProvider) refreshTokenWithCustomClientID(ctx context.Context) error {
 	// Check if token is still valid (60 second buffer)
 	if provider.token.AccessToken != "" && (time.Now().Unix() < provider.token.ExpiryTime-60) {
@@ -55,7 +73,7 @@ func (provider *S21AuthProvider) refreshTokenWithCustomClientID(ctx context.Cont
 		SetContext(ctx).
 		SetHeader("Content-Type", "application/x-www-form-urlencoded").
 		SetFormData(formData).
-		Post("https://auth.21-school.ru/auth/realms/EduPowerKeycloak/protocol/openid-connect/token")
+		Post(tokenEndpointURL)
 
 	if err != nil {
 		return fmt.Errorf("token refresh request failed: %w", err)
@@ -85,6 +103,7 @@ func (provider *S21AuthProvider) refreshTokenWithCustomClientID(ctx context.Cont
 	return nil
 }
 
+// refreshCredentials refreshes the token and fills in missing school data; caller must hold mu
 func (provider *S21AuthProvider) refreshCredentials(ctx context.Context) error {
 	if err := provider.refreshTokenWithCustomClientID(ctx); err != nil {
 		return err
@@ -97,7 +116,9 @@ func (provider *S21AuthProvider) refreshCredentials(ctx context.Context) error {
 			return err
 		}
 
+		provider.schoolIDMu.Lock()
 		provider.schoolID = user.Roles[0].SchoolID
+		provider.schoolIDMu.Unlock()
 	}
 
 	if provider.contextHeaders == nil {
@@ -117,7 +138,11 @@ func (provider *S21AuthProvider) refreshCredentials(ctx context.Context) error {
 }
 
 // GetAuthCredentials implements AuthProvider interface
+// Concurrent callers are serialized so an expired token is refreshed only once
 func (a *S21AuthProvider) GetAuthCredentials(ctx context.Context) (s21client.AuthCredentials, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
 	err := a.refreshCredentials(ctx)
 
 	if err != nil {
@@ -133,11 +158,108 @@ func (a *S21AuthProvider) GetAuthCredentials(ctx context.Context) (s21client.Aut
 	return creds, nil
 }
 
+// SetSchoolID updates the school ID sent with subsequent requests
+func (a *S21AuthProvider) SetSchoolID(schoolID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.schoolIDMu.Lock()
+	defer a.schoolIDMu.Unlock()
+
+	a.schoolID = schoolID
+}
+
+// currentSchoolID returns the school ID without waiting for an in-flight token refresh
+func (a *S21AuthProvider) currentSchoolID() string {
+	a.schoolIDMu.RLock()
+	defer a.schoolIDMu.RUnlock()
+
+	return a.schoolID
+}
+
+// SetContextHeaders updates the context headers sent with subsequent requests
+func (a *S21AuthProvider) SetContextHeaders(headers *s21client.ContextHeaders) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if headers == nil {
+		a.contextHeaders = nil
+		return
+	}
+	copied := *headers
+	a.contextHeaders = &copied
+}
+
+// SetSchoolID updates the school ID of a token-based client; no-op for credential-based clients
+func (c *S21Client) SetSchoolID(schoolID string) {
+	if c.auth != nil {
+		c.auth.SetSchoolID(schoolID)
+	}
+}
+
+// SetContextHeaders updates the context headers of a token-based client; no-op for credential-based clients
+func (c *S21Client) SetContextHeaders(headers *s21client.ContextHeaders) {
+	if c.auth != nil {
+		c.auth.SetContextHeaders(headers)
+	}
+}
+
+// SetTracer enables OpenTelemetry spans around every S21 request; nil disables tracing
+// It is not synchronized: call it once while setting up the client, before the client
+// is used or shared between goroutines.
+func (c *S21Client) SetTracer(tracer trace.Tracer) {
+	c.tracer = tracer
+}
+
+// noopEndSpan is returned by startSpan when tracing is disabled
+func noopEndSpan(error) {}
+
+// startSpan starts a span named after the S21 operation when a tracer is configured
+// The returned func ends the span, recording err if non-nil
+func (c *S21Client) startSpan(ctx context.Context, operation string) (context.Context, func(error)) {
+	if c.tracer == nil {
+		return ctx, noopEndSpan
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("s21.operation", operation)}
+	if c.login != "" {
+		attrs = append(attrs, attribute.String("s21.user", c.login))
+	}
+	if c.auth != nil {
+		if schoolID := c.auth.currentSchoolID(); schoolID != "" {
+			attrs = append(attrs, attribute.String("s21.school_id", schoolID))
+		}
+	}
+
+	ctx, span := c.tracer.Start(ctx, "s21."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// defaultClientID is the client_id used for token refresh when none is configured
+const defaultClientID = "school21"
+
+// clientIDEnvVar names the environment variable overriding the default client_id
+const clientIDEnvVar = "S21_CLIENT_ID"
+
+// resolveClientID returns clientID, falling back to S21_CLIENT_ID and then "school21"
+func resolveClientID(clientID string) string {
+	if clientID != "" {
+		return clientID
+	}
+	if envClientID := os.Getenv(clientIDEnvVar); envClientID != "" {
+		return envClientID
+	}
+	return defaultClientID
+}
+
 // NewS21Client creates a new S21 client with token-based auth (deprecated - use NewS21ClientFromTokens)
 func NewS21Client(accessToken, refreshToken, clientID string) *S21Client {
-	if clientID == "" {
-		clientID = "school21" // Default value
-	}
+	clientID = resolveClientID(clientID)
 
 	auth := &S21AuthProvider{
 		token: s21auth.Token{
@@ -149,14 +271,13 @@ func NewS21Client(accessToken, refreshToken, clientID string) *S21Client {
 
 	return &S21Client{
 		client: s21client.New(auth),
+		auth:   auth,
 	}
 }
 
 // NewS21ClientFromTokens creates a new S21 client from stored tokens with expiry tracking
 func NewS21ClientFromTokens(accessToken, refreshToken string, issueTime, expiryTime int64, clientID string) *S21Client {
-	if clientID == "" {
-		clientID = "school21" // Default value
-	}
+	clientID = resolveClientID(clientID)
 
 	auth := &S21AuthProvider{
 		token: s21auth.Token{
@@ -170,14 +291,22 @@ func NewS21ClientFromTokens(accessToken, refreshToken string, issueTime, expiryT
 
 	return &S21Client{
 		client: s21client.New(auth),
+		auth:   auth,
 	}
 }
 
+// NewS21ClientFromUserTokens creates a new S21 client from stored user tokens, keeping their expiry
+// so still-valid tokens are used without a refresh; returns nil if tokens is nil
+func NewS21ClientFromUserTokens(tokens *models.UserTokens, clientID string) *S21Client {
+	if tokens == nil {
+		return nil
+	}
+	return NewS21ClientFromTokens(tokens.AccessToken, tokens.RefreshToken, tokens.IssueTime, tokens.ExpiryTime, clientID)
+}
+
 // NewS21ClientWithSchoolID creates a new S21 client with full auth context
 func NewS21ClientWithSchoolID(accessToken, refreshToken, schoolID string, contextHeaders *s21client.ContextHeaders, clientID string) *S21Client {
-	if clientID == "" {
-		clientID = "school21" // Default value
-	}
+	clientID = resolveClientID(clientID)
 
 	auth := &S21AuthProvider{
 		token: s21auth.Token{
@@ -191,6 +320,7 @@ func NewS21ClientWithSchoolID(accessToken, refreshToken, schoolID string, contex
 
 	return &S21Client{
 		client: s21client.New(auth),
+		auth:   auth,
 	}
 }
 
@@ -199,6 +329,7 @@ func NewS21ClientFromCreds(username, password string) *S21Client {
 	auth := s21client.DefaultAuth(username, password)
 	return &S21Client{
 		client: s21client.New(auth),
+		login:  username,
 	}
 }
 
@@ -209,7 +340,9 @@ func (c *S21Client) GetCalendarEvents(ctx context.Context, from, to time.Time) (
 		To:   to.UTC(),
 	}
 
+	ctx, endSpan := c.startSpan(ctx, "CalendarGetEvents")
 	resp, err := c.client.R().SetContext(ctx).CalendarGetEvents(vars)
+	endSpan(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get calendar events: %w", err)
 	}
@@ -224,7 +357,9 @@ func (c *S21Client) GetMyBookings(ctx context.Context, from, to time.Time) (*req
 		To:   to.UTC(),
 	}
 
+	ctx, endSpan := c.startSpan(ctx, "CalendarGetMyBookings")
 	resp, err := c.client.R().SetContext(ctx).CalendarGetMyBookings(vars)
+	endSpan(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get my bookings: %w", err)
 	}
@@ -232,6 +367,21 @@ func (c *S21Client) GetMyBookings(ctx context.Context, from, to time.Time) (*req
 	return &resp, nil
 }
 
+// GetBookingsTyped fetches user's bookings as fully typed structures
+func (c *S21Client) GetBookingsTyped(ctx context.Context, from, to time.Time) ([]TypedBooking, error) {
+	data, err := c.GetMyBookings(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	bookings, err := ExtractTypedBookings(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract typed bookings: %w", err)
+	}
+
+	return bookings, nil
+}
+
 // ChangeEventSlot modifies a calendar slot timing
 func (c *S21Client) ChangeEventSlot(ctx context.Context, slotID string, start, end time.Time) error {
 	vars := requests.CalendarChangeEventSlot_Variables{
@@ -240,7 +390,9 @@ func (c *S21Client) ChangeEventSlot(ctx context.Context, slotID string, start, e
 		End:   end.UTC(),
 	}
 
+	ctx, endSpan := c.startSpan(ctx, "CalendarChangeEventSlot")
 	_, err := c.client.R().SetContext(ctx).CalendarChangeEventSlot(vars)
+	endSpan(err)
 	if err != nil {
 		return fmt.Errorf("failed to change event slot: %w", err)
 	}
@@ -248,13 +400,37 @@ func (c *S21Client) ChangeEventSlot(ctx context.Context, slotID string, start, e
 	return nil
 }
 
+// ErrSlotConflict is returned when moving a slot would overlap another existing slot
+var ErrSlotConflict = errors.New("slot conflicts with an existing slot")
+
+// ChangeEventSlotSafe moves a slot like ChangeEventSlot, but first rejects a move that would
+// overlap any other slot in existing (the slot being moved is ignored)
+func (c *S21Client) ChangeEventSlotSafe(ctx context.Context, slotID string, start, end time.Time, existing []CalendarSlot) error {
+	if !end.After(start) {
+		return fmt.Errorf("invalid slot range: end %s is not after start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	target := CalendarSlot{ID: slotID, Start: start, End: end}
+	for _, slot := range FindOverlappingSlots(target, existing) {
+		if slot.ID == slotID {
+			continue
+		}
+		return fmt.Errorf("%w: %s would overlap slot %s (%s - %s)", ErrSlotConflict, slotID, slot.ID,
+			slot.Start.Format(time.RFC3339), slot.End.Format(time.RFC3339))
+	}
+
+	return c.ChangeEventSlot(ctx, slotID, start, end)
+}
+
 // DeleteSlot deletes a calendar slot
 func (c *S21Client) DeleteSlot(ctx context.Context, slotID string) error {
 	vars := requests.CalendarDeleteEventSlot_Variables{
 		EventSlotID: slotID,
 	}
 
+	ctx, endSpan := c.startSpan(ctx, "CalendarDeleteEventSlot")
 	_, err := c.client.R().SetContext(ctx).CalendarDeleteEventSlot(vars)
+	endSpan(err)
 	if err != nil {
 		return fmt.Errorf("failed to delete slot: %w", err)
 	}
@@ -273,7 +449,9 @@ func (c *S21Client) CancelBooking(ctx context.Context, bookingID string) error {
 		BookingID: bookingID,
 	}
 
+	ctx, endSpan := c.startSpan(ctx, "RemoveP2P")
 	resp, err := c.client.R().SetContext(ctx).RemoveP2P(vars)
+	endSpan(err)
 	if err != nil {
 		return fmt.Errorf("failed to cancel booking: %w", err)
 	}
@@ -294,7 +472,9 @@ func (c *S21Client) GetNotifications(ctx context.Context, offset, limit int64) (
 		},
 	}
 
+	ctx, endSpan := c.startSpan(ctx, "GetUserNotifications")
 	resp, err := c.client.R().SetContext(ctx).GetUserNotifications(vars)
+	endSpan(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get notifications: %w", err)
 	}
@@ -302,9 +482,138 @@ func (c *S21Client) GetNotifications(ctx context.Context, offset, limit int64) (
 	return &resp, nil
 }
 
+// GetUnreadNotifications fetches user notifications and keeps only unread ones
+func (c *S21Client) GetUnreadNotifications(ctx context.Context, offset, limit int64) ([]Notification, error) {
+	data, err := c.GetNotifications(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterUnread(ExtractNotifications(data)), nil
+}
+
+// bulkNotificationsLimit is the page size FetchNotificationsForUsers requests per user
+const bulkNotificationsLimit = 100
+
+// FetchNotificationsForUsers fetches the latest notifications of every user with at most
+// concurrency requests in flight. Users not reached before ctx is done get ctx.Err().
+func FetchNotificationsForUsers(ctx context.Context, clients map[string]*S21Client, concurrency int) (map[string][]Notification, map[string]error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make(map[string][]Notification)
+	failures := make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for login, client := range clients {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			mu.Lock()
+			failures[login] = ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(login string, client *S21Client) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			notifications, err := fetchUserNotifications(ctx, client)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures[login] = err
+				return
+			}
+			results[login] = notifications
+		}(login, client)
+	}
+
+	wg.Wait()
+	return results, failures
+}
+
+// fetchUserNotifications fetches and extracts a single user's latest notifications
+func fetchUserNotifications(ctx context.Context, client *S21Client) ([]Notification, error) {
+	if client == nil {
+		return nil, fmt.Errorf("no S21 client")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := client.GetNotifications(ctx, 0, bulkNotificationsLimit)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractNotificationsCtx(ctx, data)
+}
+
+// markNotificationsReadQuery is the s21 mutation that marks notifications as read
+// s21auto-client-go v0.2.0 has no mark-read operation, so this is written by hand
+// after the s21Notification namespace used in requests/get_user_notifications.go.
+// It has not been checked against a captured platform schema; move it into the
+// client's requests package once the operation is captured there.
+const markNotificationsReadQuery = `mutation markNotificationsAsRead($notificationIds: [ID!]!) {
+  s21Notification {
+    markNotificationsAsRead(notificationIds: $notificationIds) {
+      id
+      wasRead
+      __typename
+    }
+    __typename
+  }
+}
+`
+
+// ErrNotificationNotFound is returned (wrapped) by MarkNotificationRead when the platform
+// does not report the notification as read, e.g. because the id is unknown
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// MarkNotificationRead marks a single notification as read
+func (c *S21Client) MarkNotificationRead(ctx context.Context, notificationID string) error {
+	if notificationID == "" {
+		return fmt.Errorf("notification id is required")
+	}
+
+	vars := map[string]any{
+		"notificationIds": []string{notificationID},
+	}
+
+	var out struct {
+		S21Notification struct {
+			MarkNotificationsAsRead []struct {
+				ID      string `json:"id"`
+				WasRead bool   `json:"wasRead"`
+			} `json:"markNotificationsAsRead"`
+		} `json:"s21Notification"`
+	}
+
+	if err := c.ExecuteRaw(ctx, "markNotificationsAsRead", markNotificationsReadQuery, vars, &out); err != nil {
+		return fmt.Errorf("failed to mark notification read: %w", err)
+	}
+
+	for _, n := range out.S21Notification.MarkNotificationsAsRead {
+		if n.ID == notificationID && n.WasRead {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrNotificationNotFound, notificationID)
+}
+
 // GetCurrentUser fetches current authenticated user information
 func (c *S21Client) GetCurrentUser(ctx context.Context) (*requests.GetCurrentUser_Data, error) {
+	ctx, endSpan := c.startSpan(ctx, "GetCurrentUser")
 	resp, err := c.client.R().SetContext(ctx).GetCurrentUser(requests.GetCurrentUser_Variables{})
+	endSpan(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current user: %w", err)
 	}
@@ -312,13 +621,41 @@ func (c *S21Client) GetCurrentUser(ctx context.Context) (*requests.GetCurrentUse
 	return &resp, nil
 }
 
+// ExtractCurrentUser converts a GetCurrentUser response into a models.CurrentUser
+func ExtractCurrentUser(data *requests.GetCurrentUser_Data) (*models.CurrentUser, error) {
+	if data == nil {
+		return nil, fmt.Errorf("current user response is empty")
+	}
+
+	u := data.User.GetCurrentUser
+	if u.Login == "" {
+		return nil, fmt.Errorf("current user response has no login")
+	}
+
+	var nameParts []string
+	for _, part := range []string{u.FirstName, u.MiddleName, u.LastName} {
+		if part = strings.TrimSpace(part); part != "" {
+			nameParts = append(nameParts, part)
+		}
+	}
+
+	return &models.CurrentUser{
+		ID:              u.ID,
+		Login:           u.Login,
+		FullName:        strings.Join(nameParts, " "),
+		SchoolStudentID: u.CurrentSchoolStudentID,
+	}, nil
+}
+
 // GetProjectGraph fetches project dependency graph
 func (c *S21Client) GetProjectGraph(ctx context.Context, studentID string) (*requests.ProjectMapGetStudentGraphTemplate_Data, error) {
 	vars := requests.ProjectMapGetStudentGraphTemplate_Variables{
 		StudentID: studentID,
 	}
 
+	ctx, endSpan := c.startSpan(ctx, "ProjectMapGetStudentGraphTemplate")
 	resp, err := c.client.R().SetContext(ctx).ProjectMapGetStudentGraphTemplate(vars)
+	endSpan(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get project graph: %w", err)
 	}
@@ -326,6 +663,40 @@ func (c *S21Client) GetProjectGraph(ctx context.Context, studentID string) (*req
 	return &resp, nil
 }
 
+// ExecuteRaw runs an arbitrary GraphQL operation that has no typed wrapper yet and
+// unmarshals the response "data" object into out
+func (c *S21Client) ExecuteRaw(ctx context.Context, operationName, query string, variables any, out any) error {
+	if operationName == "" {
+		return fmt.Errorf("operation name is required")
+	}
+	if query == "" {
+		return fmt.Errorf("query is required for operation %s", operationName)
+	}
+
+	request := gql.Request[any]{
+		OperationName: operationName,
+		Query:         query,
+		Variables:     variables,
+	}
+
+	ctx, endSpan := c.startSpan(ctx, operationName)
+	data, err := requests.GqlRequest[json.RawMessage](c.client.R().SetContext(ctx), request)
+	endSpan(err)
+	if err != nil {
+		return fmt.Errorf("failed to execute %s: %w", operationName, err)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", operationName, err)
+	}
+
+	return nil
+}
+
 // ExtractFamilies extracts project families from graph response
 func ExtractFamilies(graph *requests.ProjectMapGetStudentGraphTemplate_Data) ([]*models.ProjectFamily, error) {
 	var families []*models.ProjectFamily
@@ -354,6 +725,62 @@ func ExtractFamilies(graph *requests.ProjectMapGetStudentGraphTemplate_Data) ([]
 	return families, nil
 }
 
+// Project kinds of graph items
+const (
+	ProjectKindGoal   = "GOAL"
+	ProjectKindCourse = "COURSE"
+)
+
+// ProjectFamilyDetail represents a project in the graph with its kind and position
+type ProjectFamilyDetail struct {
+	FamilyLabel string
+	ProjectName string
+	ProjectID   int64
+	Kind        string // ProjectKindGoal or ProjectKindCourse
+	IsMandatory bool
+	NodeIndex   int   // Index of the family node in the graph
+	ItemIndex   int   // Index of the item within its node
+	PositionX   int64 // Node position on the project map
+	PositionY   int64
+}
+
+// ExtractFamiliesDetailed extracts projects with their kind and graph position, in graph order
+func ExtractFamiliesDetailed(graph *requests.ProjectMapGetStudentGraphTemplate_Data) []ProjectFamilyDetail {
+	if graph == nil {
+		return nil
+	}
+
+	var details []ProjectFamilyDetail
+	for nodeIndex, node := range graph.HolyGraph.GetStudentGraphTemplate.Nodes {
+		for itemIndex, item := range node.Items {
+			var project *requests.ProjectMapGetStudentGraphTemplate_Data_Course
+			var kind string
+
+			if item.Goal != nil && item.Goal.ProjectName != "" {
+				project, kind = item.Goal, ProjectKindGoal
+			} else if item.Course != nil && item.Course.ProjectName != "" {
+				project, kind = item.Course, ProjectKindCourse
+			} else {
+				continue
+			}
+
+			details = append(details, ProjectFamilyDetail{
+				FamilyLabel: node.Label,
+				ProjectName: project.ProjectName,
+				ProjectID:   project.ProjectID,
+				Kind:        kind,
+				IsMandatory: project.IsMandatory,
+				NodeIndex:   nodeIndex,
+				ItemIndex:   itemIndex,
+				PositionX:   node.Position.X,
+				PositionY:   node.Position.Y,
+			})
+		}
+	}
+
+	return details
+}
+
 // GetFamilyLabels extracts all family labels from graph
 func GetFamilyLabels(graph *requests.ProjectMapGetStudentGraphTemplate_Data) []string {
 	labels := make([]string, 0, len(graph.HolyGraph.GetStudentGraphTemplate.Nodes))
@@ -391,9 +818,15 @@ func GetProjectsInFamily(graph *requests.ProjectMapGetStudentGraphTemplate_Data,
 	return projects
 }
 
+// Authentication failure classes returned (wrapped) by Authenticate
+var (
+	ErrInvalidCredentials = errors.New("invalid s21 credentials")
+	ErrAuthUnavailable    = errors.New("s21 auth service unavailable")
+)
+
 // Authenticate performs authentication with username/password
 func Authenticate(ctx context.Context, username, password string) (*models.TokenResponse, error) {
-	token, err := s21auth.RequestToken(username, password, ctx)
+	token, err := requestPasswordToken(ctx, username, password)
 	if err != nil {
 		return nil, fmt.Errorf("authentication failed: %w", err)
 	}
@@ -414,30 +847,131 @@ func Authenticate(ctx context.Context, username, password string) (*models.Token
 	}, nil
 }
 
+// requestPasswordToken exchanges username/password for a token, classifying failures
+// as ErrInvalidCredentials or ErrAuthUnavailable while keeping the detail
+func requestPasswordToken(ctx context.Context, username, password string) (s21auth.Token, error) {
+	res, err := resty.New().R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/x-www-form-urlencoded").
+		SetFormData(map[string]string{
+			"client_id":  resolveClientID(""),
+			"grant_type": "password",
+			"username":   username,
+			"password":   password,
+		}).
+		Post(tokenEndpointURL)
+	if err != nil {
+		return s21auth.Token{}, fmt.Errorf("%w: token request failed: %w", ErrAuthUnavailable, err)
+	}
+
+	// Error responses are decoded too, so an invalid_grant body is classified below
+	var tokenResponse models.TokenResponse
+	decodeErr := json.Unmarshal(res.Body(), &tokenResponse)
+
+	switch {
+	case res.StatusCode() == http.StatusUnauthorized, tokenResponse.Error == "invalid_grant":
+		return s21auth.Token{}, fmt.Errorf("%w: token request failed with status %d: %s", ErrInvalidCredentials, res.StatusCode(), res.String())
+	case res.StatusCode() >= http.StatusInternalServerError:
+		return s21auth.Token{}, fmt.Errorf("%w: token request failed with status %d: %s", ErrAuthUnavailable, res.StatusCode(), res.String())
+	case !res.IsSuccess():
+		return s21auth.Token{}, fmt.Errorf("token request failed with status %d: %s", res.StatusCode(), res.String())
+	case decodeErr != nil:
+		return s21auth.Token{}, fmt.Errorf("failed to parse token response: %w", decodeErr)
+	case tokenResponse.Error != "":
+		return s21auth.Token{}, fmt.Errorf("unable to get access token: %s", tokenResponse.Error)
+	}
+
+	issueTime := time.Now().Unix()
+	return s21auth.Token{
+		AccessToken:  tokenResponse.AccessToken,
+		RefreshToken: tokenResponse.RefreshToken,
+		Username:     username,
+		Password:     password,
+		IssueTime:    issueTime,
+		ExpiryTime:   issueTime + tokenResponse.ExpiresIn,
+	}, nil
+}
+
 // CalendarSlot represents a simplified calendar slot from API response
 type CalendarSlot struct {
-	ID    string
-	Start time.Time
-	End   time.Time
-	Type  string
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Type  string    `json:"type"`
 }
 
 // CalendarBooking represents a simplified booking from API response
 type CalendarBooking struct {
-	ID          string
-	BookingID   string
-	EventSlotID string
-	Start       time.Time
-	End         time.Time
-	ProjectName string
+	ID          string    `json:"id"`
+	BookingID   string    `json:"booking_id"`
+	EventSlotID string    `json:"event_slot_id"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	ProjectName string    `json:"project_name"`
+}
+
+// ToModel converts the slot to its models representation with unix-second times
+func (s CalendarSlot) ToModel() models.CalendarSlot {
+	return models.CalendarSlot{
+		ID:    s.ID,
+		Start: s.Start.Unix(),
+		End:   s.End.Unix(),
+		Type:  s.Type,
+	}
+}
+
+// CalendarSlotFromModel converts a models slot to a CalendarSlot with UTC times
+func CalendarSlotFromModel(s models.CalendarSlot) CalendarSlot {
+	return CalendarSlot{
+		ID:    s.ID,
+		Start: time.Unix(s.Start, 0).UTC(),
+		End:   time.Unix(s.End, 0).UTC(),
+		Type:  s.Type,
+	}
+}
+
+// ToModel converts the booking to its models representation with unix-second times
+func (b CalendarBooking) ToModel() models.CalendarBooking {
+	return models.CalendarBooking{
+		ID:          b.ID,
+		BookingID:   b.BookingID,
+		EventSlotID: b.EventSlotID,
+		StartTime:   b.Start.Unix(),
+		EndTime:     b.End.Unix(),
+		ProjectName: b.ProjectName,
+	}
+}
+
+// CalendarBookingFromModel converts a models booking to a CalendarBooking with UTC times
+func CalendarBookingFromModel(b models.CalendarBooking) CalendarBooking {
+	return CalendarBooking{
+		ID:          b.ID,
+		BookingID:   b.BookingID,
+		EventSlotID: b.EventSlotID,
+		Start:       time.Unix(b.StartTime, 0).UTC(),
+		End:         time.Unix(b.EndTime, 0).UTC(),
+		ProjectName: b.ProjectName,
+	}
 }
 
 // ExtractSlots extracts free time slots from calendar events
 func ExtractSlots(data *requests.CalendarGetEvents_Data) []CalendarSlot {
+	slots, _ := ExtractSlotsCtx(context.Background(), data)
+	return slots
+}
+
+// ExtractSlotsCtx is ExtractSlots that stops early with the partial result when ctx is done
+func ExtractSlotsCtx(ctx context.Context, data *requests.CalendarGetEvents_Data) ([]CalendarSlot, error) {
 	var slots []CalendarSlot
+	processed := 0
 
 	for _, event := range data.CalendarEventS21.GetMyCalendarEvents {
 		for _, slot := range event.EventSlots {
+			if err := checkExtractCtx(ctx, processed); err != nil {
+				return slots, err
+			}
+			processed++
+
 			slots = append(slots, CalendarSlot{
 				ID:    slot.ID,
 				Start: slot.Start,
@@ -447,15 +981,130 @@ func ExtractSlots(data *requests.CalendarGetEvents_Data) []CalendarSlot {
 		}
 	}
 
-	return slots
+	return slots, nil
+}
+
+// SlotsOverlap reports whether two slots overlap using half-open [start, end) intervals
+func SlotsOverlap(a, b CalendarSlot) bool {
+	if !a.End.After(a.Start) || !b.End.After(b.Start) {
+		return false
+	}
+	return a.Start.Before(b.End) && b.Start.Before(a.End)
+}
+
+// FindOverlappingSlots returns the existing slots that overlap the target slot
+func FindOverlappingSlots(target CalendarSlot, existing []CalendarSlot) []CalendarSlot {
+	var overlapping []CalendarSlot
+	for _, slot := range existing {
+		if SlotsOverlap(target, slot) {
+			overlapping = append(overlapping, slot)
+		}
+	}
+	return overlapping
+}
+
+// SortSlotsByStart stable-sorts slots ascending by start time
+func SortSlotsByStart(slots []CalendarSlot) {
+	sort.SliceStable(slots, func(i, j int) bool {
+		return slots[i].Start.Before(slots[j].Start)
+	})
+}
+
+// SortBookingsByStart stable-sorts bookings ascending by start time
+func SortBookingsByStart(bookings []CalendarBooking) {
+	sort.SliceStable(bookings, func(i, j int) bool {
+		return bookings[i].Start.Before(bookings[j].Start)
+	})
+}
+
+// ReconcileSlots computes the mutations that turn actual into desired. A desired slot is matched
+// to an actual slot by ID first, then by identical time window; an ID match with a different window
+// becomes a change pair {actual, desired}. Unmatched desired slots are created and unmatched actual
+// slots are deleted. Results keep the input order.
+func ReconcileSlots(desired, actual []CalendarSlot) (toCreate, toDelete []CalendarSlot, toChange [][2]CalendarSlot) {
+	matched := make([]bool, len(actual))
+	actualByID := make(map[string]int, len(actual))
+	for i, slot := range actual {
+		if slot.ID != "" {
+			actualByID[slot.ID] = i
+		}
+	}
+
+	var unmatchedDesired []CalendarSlot
+	for _, want := range desired {
+		i, ok := actualByID[want.ID]
+		if want.ID == "" || !ok || matched[i] {
+			unmatchedDesired = append(unmatchedDesired, want)
+			continue
+		}
+		matched[i] = true
+		if !sameSlotWindow(actual[i], want) {
+			toChange = append(toChange, [2]CalendarSlot{actual[i], want})
+		}
+	}
+
+	for _, want := range unmatchedDesired {
+		found := false
+		for i, have := range actual {
+			if !matched[i] && sameSlotWindow(have, want) {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			toCreate = append(toCreate, want)
+		}
+	}
+
+	for i, have := range actual {
+		if !matched[i] {
+			toDelete = append(toDelete, have)
+		}
+	}
+
+	return toCreate, toDelete, toChange
+}
+
+// sameSlotWindow reports whether two slots cover exactly the same time window
+func sameSlotWindow(a, b CalendarSlot) bool {
+	return a.Start.Equal(b.Start) && a.End.Equal(b.End)
+}
+
+// FindEarliestFreeSlot returns the earliest FREE_TIME slot starting at or after the given time
+// that lasts at least minDuration
+func FindEarliestFreeSlot(slots []CalendarSlot, after time.Time, minDuration time.Duration) (*CalendarSlot, bool) {
+	var earliest *CalendarSlot
+	for i := range slots {
+		slot := slots[i]
+		if slot.Type != models.SlotTypeFreeTime || slot.Start.Before(after) || slot.End.Sub(slot.Start) < minDuration {
+			continue
+		}
+		if earliest == nil || slot.Start.Before(earliest.Start) {
+			earliest = &slot
+		}
+	}
+	return earliest, earliest != nil
 }
 
 // ExtractBookings extracts bookings from calendar events
 func ExtractBookings(data *requests.CalendarGetEvents_Data) []CalendarBooking {
+	bookings, _ := ExtractBookingsCtx(context.Background(), data)
+	return bookings
+}
+
+// ExtractBookingsCtx is ExtractBookings that stops early with the partial result when ctx is done
+func ExtractBookingsCtx(ctx context.Context, data *requests.CalendarGetEvents_Data) ([]CalendarBooking, error) {
 	var bookings []CalendarBooking
+	processed := 0
 
 	for _, event := range data.CalendarEventS21.GetMyCalendarEvents {
 		for _, b := range event.Bookings {
+			if err := checkExtractCtx(ctx, processed); err != nil {
+				return bookings, err
+			}
+			processed++
+
 			if bookingMap, ok := b.(map[string]interface{}); ok {
 				booking := CalendarBooking{}
 
@@ -493,7 +1142,7 @@ func ExtractBookings(data *requests.CalendarGetEvents_Data) []CalendarBooking {
 		}
 	}
 
-	return bookings
+	return bookings, nil
 }
 
 // ExtractBookingsFromMyBookings extracts bookings from GetMyBookings API response
@@ -606,22 +1255,133 @@ func ExtractBookingsFromMyBookings(data *requests.CalendarGetMyBookings_Data) []
 	return bookings
 }
 
+// TypedBooking represents a fully typed booking from GetMyBookings API response
+type TypedBooking struct {
+	ID             string              `json:"id"`
+	AnswerID       string              `json:"answerId"`
+	EventSlotID    string              `json:"eventSlotId"`
+	Task           TypedBookingTask    `json:"task"`
+	EventSlot      TypedBookingSlot    `json:"eventSlot"`
+	VerifierUser   TypedBookingUser    `json:"verifierUser"`
+	VerifiableInfo TypedVerifiableInfo `json:"verifiableInfo"`
+	BookingStatus  string              `json:"bookingStatus"`
+	IsOnline       bool                `json:"isOnline"`
+	VcLinkUrl      string              `json:"vcLinkUrl"`
+}
+
+// TypedBookingTask represents task information within a typed booking
+type TypedBookingTask struct {
+	ID             string `json:"id"`
+	GoalID         string `json:"goalId"`
+	GoalName       string `json:"goalName"`
+	AssignmentType string `json:"assignmentType"`
+}
+
+// TypedBookingSlot represents event slot information within a typed booking
+type TypedBookingSlot struct {
+	ID    string    `json:"id"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// TypedBookingUser represents a user referenced by a typed booking
+type TypedBookingUser struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	Login  string `json:"login"`
+}
+
+// TypedVerifiableInfo represents the students being verified in a typed booking
+type TypedVerifiableInfo struct {
+	VerifiableStudents []TypedBookingUser `json:"verifiableStudents"`
+	Team               *TypedBookingTeam  `json:"team"`
+}
+
+// TypedBookingTeam represents a team being verified in a typed booking
+type TypedBookingTeam struct {
+	Name string `json:"name"`
+}
+
+// StudentLogins returns logins of all verifiable students of the booking
+func (b TypedBooking) StudentLogins() []string {
+	var logins []string
+	for _, s := range b.VerifiableInfo.VerifiableStudents {
+		if s.Login != "" {
+			logins = append(logins, s.Login)
+		}
+	}
+	return logins
+}
+
+// StudentLogin returns login of the first verifiable student, or empty string
+func (b TypedBooking) StudentLogin() string {
+	logins := b.StudentLogins()
+	if len(logins) == 0 {
+		return ""
+	}
+	return logins[0]
+}
+
+// ExtractTypedBookings extracts fully typed bookings from GetMyBookings API response
+func ExtractTypedBookings(data *requests.CalendarGetMyBookings_Data) ([]TypedBooking, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	var bookings []TypedBooking
+	for i, b := range data.Student.GetMyCalendarBookings {
+		if b == nil {
+			continue
+		}
+
+		var booking TypedBooking
+		if err := review.ConvertInterface(b, &booking); err != nil {
+			return nil, fmt.Errorf("failed to convert booking %d: %w", i, err)
+		}
+
+		if booking.ID == "" {
+			continue
+		}
+		bookings = append(bookings, booking)
+	}
+
+	return bookings, nil
+}
+
 // Notification represents a notification from API response
 type Notification struct {
-	ID                string
-	RelatedObjectType string
-	RelatedObjectID   string
-	Message           string
-	Time              time.Time
-	WasRead           bool
-	GroupName         string
+	ID                string    `json:"id"`
+	RelatedObjectType string    `json:"related_object_type"`
+	RelatedObjectID   string    `json:"related_object_id"`
+	Message           string    `json:"message"`
+	Time              time.Time `json:"time"`
+	WasRead           bool      `json:"was_read"`
+	GroupName         string    `json:"group_name"`
+}
+
+// NotificationTypeBooking is the RelatedObjectType the platform returns for review bookings
+const NotificationTypeBooking = "BOOKING"
+
+// IsReviewNotification reports whether a notification refers to a review booking
+func IsReviewNotification(n Notification) bool {
+	return n.RelatedObjectID != "" && n.RelatedObjectType == NotificationTypeBooking
 }
 
 // ExtractNotifications extracts notifications from API response
 func ExtractNotifications(data *requests.GetUserNotifications_Data) []Notification {
+	notifications, _ := ExtractNotificationsCtx(context.Background(), data)
+	return notifications
+}
+
+// ExtractNotificationsCtx is ExtractNotifications that stops early with the partial result when ctx is done
+func ExtractNotificationsCtx(ctx context.Context, data *requests.GetUserNotifications_Data) ([]Notification, error) {
 	var notifications []Notification
 
-	for _, n := range data.S21Notification.GetS21Notifications.Notifications {
+	for i, n := range data.S21Notification.GetS21Notifications.Notifications {
+		if err := checkExtractCtx(ctx, i); err != nil {
+			return notifications, err
+		}
+
 		notifications = append(notifications, Notification{
 			ID:                n.ID,
 			RelatedObjectType: n.RelatedObjectType,
@@ -633,7 +1393,31 @@ func ExtractNotifications(data *requests.GetUserNotifications_Data) []Notificati
 		})
 	}
 
-	return notifications
+	return notifications, nil
+}
+
+// extractCtxCheckInterval is how many entries the Extract*Ctx helpers process between ctx checks
+const extractCtxCheckInterval = 64
+
+// checkExtractCtx returns ctx.Err() every extractCtxCheckInterval entries
+func checkExtractCtx(ctx context.Context, processed int) error {
+	if processed%extractCtxCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// FilterUnread returns notifications that have not been read yet
+func FilterUnread(notifications []Notification) []Notification {
+	var unread []Notification
+
+	for _, n := range notifications {
+		if !n.WasRead {
+			unread = append(unread, n)
+		}
+	}
+
+	return unread
 }
 
 // FindNotificationBySlotID finds a notification matching a calendar slot ID and time
@@ -1,11 +1,29 @@
 package external
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	s21client "github.com/arseniisemenow/s21auto-client-go"
+	s21auth "github.com/arseniisemenow/s21auto-client-go/auth"
+	"github.com/arseniisemenow/s21auto-client-go/requests"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
 	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 )
@@ -150,6 +168,126 @@ func TestNotificationStructure(t *testing.T) {
 	assert.False(t, notif.WasRead)
 }
 
+func TestIsReviewNotification(t *testing.T) {
+	review := Notification{
+		ID:                "notif-1",
+		RelatedObjectType: NotificationTypeBooking,
+		RelatedObjectID:   "slot-123",
+		Message:           "Review requested",
+	}
+	assert.True(t, IsReviewNotification(review))
+
+	unrelated := Notification{
+		ID:                "notif-2",
+		RelatedObjectType: "",
+		RelatedObjectID:   "",
+		Message:           "Scheduled maintenance",
+	}
+	assert.False(t, IsReviewNotification(unrelated))
+
+	missingID := review
+	missingID.RelatedObjectID = ""
+	assert.False(t, IsReviewNotification(missingID))
+
+	t.Run("BOOKING from an API response", func(t *testing.T) {
+		client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"s21Notification":{"getS21Notifications":{"notifications":[` +
+				`{"id":"notif-1","relatedObjectType":"BOOKING","relatedObjectId":"slot-1","message":"You have been booked for a review","time":"2025-01-08T14:00:00Z","wasRead":false,"groupName":"Reviews"},` +
+				`{"id":"notif-2","relatedObjectType":null,"relatedObjectId":null,"message":"Scheduled maintenance","time":"2025-01-08T15:00:00Z","wasRead":false,"groupName":"System"}` +
+				`],"totalCount":2,"groupNames":["Reviews","System"]}}}}`))
+		})
+
+		unread, err := client.GetUnreadNotifications(context.Background(), 0, 10)
+
+		require.NoError(t, err)
+		require.Len(t, unread, 2)
+		assert.Equal(t, NotificationTypeBooking, unread[0].RelatedObjectType)
+		assert.True(t, IsReviewNotification(unread[0]))
+		assert.False(t, IsReviewNotification(unread[1]))
+	})
+}
+
+func TestFilterUnread(t *testing.T) {
+	notifications := []Notification{
+		{ID: "notif-1", WasRead: true},
+		{ID: "notif-2", WasRead: false},
+		{ID: "notif-3", WasRead: true},
+		{ID: "notif-4", WasRead: false},
+	}
+
+	unread := FilterUnread(notifications)
+
+	require.Len(t, unread, 2)
+	assert.Equal(t, "notif-2", unread[0].ID)
+	assert.Equal(t, "notif-4", unread[1].ID)
+	assert.Empty(t, FilterUnread(nil))
+}
+
+func TestGetUnreadNotifications(t *testing.T) {
+	client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"s21Notification":{"getS21Notifications":{"notifications":[` +
+			`{"id":"notif-1","relatedObjectType":"BOOKING","relatedObjectId":"slot-1","message":"read","time":"2025-01-08T14:00:00Z","wasRead":true,"groupName":"Reviews"},` +
+			`{"id":"notif-2","relatedObjectType":"BOOKING","relatedObjectId":"slot-2","message":"unread","time":"2025-01-08T15:00:00Z","wasRead":false,"groupName":"Reviews"}` +
+			`],"totalCount":2,"groupNames":["Reviews"]}}}}`))
+	})
+
+	unread, err := client.GetUnreadNotifications(context.Background(), 0, 10)
+
+	require.NoError(t, err)
+	require.Len(t, unread, 1)
+	assert.Equal(t, "notif-2", unread[0].ID)
+	assert.Equal(t, "slot-2", unread[0].RelatedObjectID)
+}
+
+func TestMarkNotificationRead(t *testing.T) {
+	t.Run("sends notification id in mutation variables", func(t *testing.T) {
+		var received struct {
+			OperationName string `json:"operationName"`
+			Variables     struct {
+				NotificationIDs []string `json:"notificationIds"`
+			} `json:"variables"`
+		}
+
+		client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(body, &received))
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"s21Notification":{"markNotificationsAsRead":[{"id":"notif-1","wasRead":true}]}}}`))
+		})
+
+		err := client.MarkNotificationRead(context.Background(), "notif-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "markNotificationsAsRead", received.OperationName)
+		assert.Equal(t, []string{"notif-1"}, received.Variables.NotificationIDs)
+	})
+
+	t.Run("unknown id returns error", func(t *testing.T) {
+		client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"s21Notification":{"markNotificationsAsRead":[]}}}`))
+		})
+
+		err := client.MarkNotificationRead(context.Background(), "missing")
+
+		assert.ErrorIs(t, err, ErrNotificationNotFound)
+		assert.Contains(t, err.Error(), "missing")
+	})
+
+	t.Run("empty id is rejected", func(t *testing.T) {
+		client := &S21Client{}
+
+		err := client.MarkNotificationRead(context.Background(), "")
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "notification id is required")
+	})
+}
+
 // Test that CalendarSlot and CalendarBooking structs have the right fields
 func TestSlotAndBookingFields(t *testing.T) {
 	slot := CalendarSlot{
@@ -182,3 +320,960 @@ func TestExtractFamiliesEmpty(t *testing.T) {
 	// For now, just verify the function exists
 	t.Skip("Requires full graph response mock")
 }
+
+// newStubbedS21Client creates a client whose GraphQL requests go to handler
+// The auth provider is pre-populated so no token refresh or lookup happens
+func newStubbedS21Client(t *testing.T, handler http.HandlerFunc) *S21Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	originalURL := s21client.S21GqlUrl
+	s21client.S21GqlUrl = server.URL
+	t.Cleanup(func() { s21client.S21GqlUrl = originalURL })
+
+	auth := &S21AuthProvider{
+		token: s21auth.Token{
+			AccessToken:  "access_token",
+			RefreshToken: "refresh_token",
+			IssueTime:    time.Now().Unix(),
+			ExpiryTime:   time.Now().Add(time.Hour).Unix(),
+		},
+		schoolID:       "school123",
+		contextHeaders: &s21client.ContextHeaders{XEDUSchoolID: "school123"},
+		clientID:       "school21",
+	}
+
+	return &S21Client{client: s21client.New(auth), auth: auth}
+}
+
+func TestExecuteRaw(t *testing.T) {
+	t.Run("forwards operation name and variables", func(t *testing.T) {
+		var received struct {
+			OperationName string                 `json:"operationName"`
+			Query         string                 `json:"query"`
+			Variables     map[string]interface{} `json:"variables"`
+		}
+
+		client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(body, &received))
+			assert.Equal(t, "Bearer access_token", r.Header.Get("Authorization"))
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"student":{"login":"testuser"}}}`))
+		})
+
+		var out struct {
+			Student struct {
+				Login string `json:"login"`
+			} `json:"student"`
+		}
+		query := "query getStudent($id: ID!) { student(id: $id) { login } }"
+		err := client.ExecuteRaw(context.Background(), "getStudent", query, map[string]string{"id": "student-1"}, &out)
+
+		require.NoError(t, err)
+		assert.Equal(t, "getStudent", received.OperationName)
+		assert.Equal(t, query, received.Query)
+		assert.Equal(t, "student-1", received.Variables["id"])
+		assert.Equal(t, "testuser", out.Student.Login)
+	})
+
+	t.Run("nil out discards data", func(t *testing.T) {
+		client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"data":{"ok":true}}`))
+		})
+
+		err := client.ExecuteRaw(context.Background(), "ping", "mutation ping { ok }", nil, nil)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("missing operation name", func(t *testing.T) {
+		client := NewS21Client("access_token", "refresh_token", "")
+
+		err := client.ExecuteRaw(context.Background(), "", "query x { y }", nil, nil)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("missing query", func(t *testing.T) {
+		client := NewS21Client("access_token", "refresh_token", "")
+
+		err := client.ExecuteRaw(context.Background(), "getStudent", "", nil, nil)
+
+		assert.Error(t, err)
+	})
+}
+
+const typedBookingsResponse = `{"data":{"student":{"getMyCalendarBookings":[{
+	"id":"booking-1","answerId":"answer-1","eventSlotId":"slot-1",
+	"task":{"id":"task-1","goalId":"goal-1","goalName":"C2_s21_stringplus","assignmentType":"INDIVIDUAL"},
+	"eventSlot":{"id":"slot-1","start":"2024-01-15T10:00:00Z","end":"2024-01-15T10:30:00Z"},
+	"verifierUser":{"id":"verifier-1","login":"reviewer"},
+	"verifiableInfo":{"verifiableStudents":[{"userId":"student-1","login":"student"}],"team":null},
+	"bookingStatus":"APPROVED","isOnline":true,"vcLinkUrl":"https://meet.example.com/abc"
+}]}}}`
+
+func TestExtractTypedBookings(t *testing.T) {
+	t.Run("populates typed fields", func(t *testing.T) {
+		var data requests.CalendarGetMyBookings_Data
+		var envelope struct {
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(typedBookingsResponse), &envelope))
+		require.NoError(t, json.Unmarshal(envelope.Data, &data))
+
+		bookings, err := ExtractTypedBookings(&data)
+
+		require.NoError(t, err)
+		require.Len(t, bookings, 1)
+		b := bookings[0]
+		assert.Equal(t, "booking-1", b.ID)
+		assert.Equal(t, "answer-1", b.AnswerID)
+		assert.Equal(t, "task-1", b.Task.ID)
+		assert.Equal(t, "C2_s21_stringplus", b.Task.GoalName)
+		assert.Equal(t, "APPROVED", b.BookingStatus)
+		assert.Equal(t, "reviewer", b.VerifierUser.Login)
+		assert.Equal(t, "student", b.StudentLogin())
+		assert.Equal(t, []string{"student"}, b.StudentLogins())
+		assert.True(t, b.IsOnline)
+		assert.Equal(t, time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC), b.EventSlot.Start.UTC())
+		assert.Nil(t, b.VerifiableInfo.Team)
+	})
+
+	t.Run("skips nil and id-less entries", func(t *testing.T) {
+		data := &requests.CalendarGetMyBookings_Data{}
+		data.Student.GetMyCalendarBookings = []interface{}{
+			nil,
+			map[string]interface{}{"eventSlotId": "slot-1"},
+			map[string]interface{}{"id": "booking-2", "bookingStatus": "PENDING"},
+		}
+
+		bookings, err := ExtractTypedBookings(data)
+
+		require.NoError(t, err)
+		require.Len(t, bookings, 1)
+		assert.Equal(t, "booking-2", bookings[0].ID)
+		assert.Equal(t, "", bookings[0].StudentLogin())
+	})
+
+	t.Run("invalid entry returns error", func(t *testing.T) {
+		data := &requests.CalendarGetMyBookings_Data{}
+		data.Student.GetMyCalendarBookings = []interface{}{
+			map[string]interface{}{"id": "booking-3", "eventSlot": map[string]interface{}{"start": "not-a-time"}},
+		}
+
+		_, err := ExtractTypedBookings(data)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("nil data", func(t *testing.T) {
+		bookings, err := ExtractTypedBookings(nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, bookings)
+	})
+}
+
+func TestGetBookingsTyped(t *testing.T) {
+	client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(typedBookingsResponse))
+	})
+
+	bookings, err := client.GetBookingsTyped(context.Background(), time.Now(), time.Now().Add(24*time.Hour))
+
+	require.NoError(t, err)
+	require.Len(t, bookings, 1)
+	assert.Equal(t, "task-1", bookings[0].Task.ID)
+	assert.Equal(t, "student", bookings[0].StudentLogin())
+	assert.Equal(t, "APPROVED", bookings[0].BookingStatus)
+}
+
+func TestSlotsOverlap(t *testing.T) {
+	base := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+	slot := func(startMin, endMin int) CalendarSlot {
+		return CalendarSlot{
+			Start: base.Add(time.Duration(startMin) * time.Minute),
+			End:   base.Add(time.Duration(endMin) * time.Minute),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		a        CalendarSlot
+		b        CalendarSlot
+		expected bool
+	}{
+		{"touching at end", slot(0, 30), slot(30, 60), false},
+		{"touching at start", slot(30, 60), slot(0, 30), false},
+		{"disjoint", slot(0, 15), slot(45, 60), false},
+		{"partial overlap", slot(0, 40), slot(30, 60), true},
+		{"partial overlap reversed", slot(30, 60), slot(0, 40), true},
+		{"full containment", slot(0, 60), slot(15, 30), true},
+		{"contained in other", slot(15, 30), slot(0, 60), true},
+		{"identical", slot(0, 30), slot(0, 30), true},
+		{"empty slot inside other", slot(15, 15), slot(0, 30), false},
+		{"inverted slot", slot(30, 0), slot(0, 30), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, SlotsOverlap(tt.a, tt.b))
+		})
+	}
+}
+
+func TestFindOverlappingSlots(t *testing.T) {
+	base := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+	existing := []CalendarSlot{
+		{ID: "before", Start: base.Add(-time.Hour), End: base},
+		{ID: "partial", Start: base.Add(-15 * time.Minute), End: base.Add(15 * time.Minute)},
+		{ID: "inside", Start: base.Add(10 * time.Minute), End: base.Add(20 * time.Minute)},
+		{ID: "after", Start: base.Add(30 * time.Minute), End: base.Add(time.Hour)},
+	}
+	target := CalendarSlot{Start: base, End: base.Add(30 * time.Minute)}
+
+	overlapping := FindOverlappingSlots(target, existing)
+
+	require.Len(t, overlapping, 2)
+	assert.Equal(t, "partial", overlapping[0].ID)
+	assert.Equal(t, "inside", overlapping[1].ID)
+	assert.Empty(t, FindOverlappingSlots(target, nil))
+}
+
+func TestS21AuthProvider_Setters(t *testing.T) {
+	newProvider := func() *S21AuthProvider {
+		return &S21AuthProvider{
+			token: s21auth.Token{
+				AccessToken:  "access_token",
+				RefreshToken: "refresh_token",
+				IssueTime:    time.Now().Unix(),
+				ExpiryTime:   time.Now().Add(time.Hour).Unix(),
+			},
+			schoolID:       "school-old",
+			contextHeaders: &s21client.ContextHeaders{XEDUSchoolID: "school-old"},
+			clientID:       "school21",
+		}
+	}
+
+	t.Run("credentials reflect updated values", func(t *testing.T) {
+		provider := newProvider()
+		headers := &s21client.ContextHeaders{XEDUSchoolID: "school-new", XEDUProductID: "product-new"}
+
+		provider.SetSchoolID("school-new")
+		provider.SetContextHeaders(headers)
+		headers.XEDUProductID = "mutated"
+
+		creds, err := provider.GetAuthCredentials(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "school-new", creds.SchoolId)
+		require.NotNil(t, creds.ContextHeaders)
+		assert.Equal(t, "school-new", creds.ContextHeaders.XEDUSchoolID)
+		assert.Equal(t, "product-new", creds.ContextHeaders.XEDUProductID)
+	})
+
+	t.Run("client setters update its provider", func(t *testing.T) {
+		provider := newProvider()
+		client := &S21Client{client: s21client.New(provider), auth: provider}
+
+		client.SetSchoolID("school-client")
+		client.SetContextHeaders(&s21client.ContextHeaders{XEDUSchoolID: "school-client"})
+
+		creds, err := provider.GetAuthCredentials(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "school-client", creds.SchoolId)
+		assert.Equal(t, "school-client", creds.ContextHeaders.XEDUSchoolID)
+	})
+
+	t.Run("credential-based client ignores setters", func(t *testing.T) {
+		client := NewS21ClientFromCreds("username", "password")
+
+		assert.NotPanics(t, func() {
+			client.SetSchoolID("school")
+			client.SetContextHeaders(nil)
+		})
+	})
+}
+
+func TestS21AuthProvider_ConcurrentRefresh(t *testing.T) {
+	var refreshes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  "refreshed_access_token",
+			"refresh_token": "refreshed_refresh_token",
+			"expires_in":    300,
+			"token_type":    "Bearer",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	originalURL := tokenEndpointURL
+	tokenEndpointURL = server.URL
+	t.Cleanup(func() { tokenEndpointURL = originalURL })
+
+	provider := &S21AuthProvider{
+		token: s21auth.Token{
+			AccessToken:  "expired_access_token",
+			RefreshToken: "refresh_token",
+			IssueTime:    time.Now().Add(-2 * time.Hour).Unix(),
+			ExpiryTime:   time.Now().Add(-time.Hour).Unix(),
+		},
+		schoolID:       "school123",
+		contextHeaders: &s21client.ContextHeaders{XEDUSchoolID: "school123"},
+		clientID:       "school21",
+	}
+
+	const workers = 20
+	var wg sync.WaitGroup
+	tokens := make([]string, workers)
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			creds, err := provider.GetAuthCredentials(context.Background())
+			tokens[i] = creds.Token
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), refreshes.Load())
+	for i := 0; i < workers; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "refreshed_access_token", tokens[i])
+	}
+}
+
+func TestCalendarModelConversion(t *testing.T) {
+	moscow := time.FixedZone("UTC+3", 3*3600)
+
+	t.Run("slot round trip", func(t *testing.T) {
+		slot := CalendarSlot{
+			ID:    "slot-123",
+			Start: time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC),
+			End:   time.Date(2025, 1, 8, 15, 0, 0, 0, time.UTC),
+			Type:  models.SlotTypeFreeTime,
+		}
+
+		model := slot.ToModel()
+		assert.Equal(t, models.CalendarSlot{ID: "slot-123", Start: slot.Start.Unix(), End: slot.End.Unix(), Type: models.SlotTypeFreeTime}, model)
+		assert.Equal(t, slot, CalendarSlotFromModel(model))
+		assert.Equal(t, model, CalendarSlotFromModel(model).ToModel())
+	})
+
+	t.Run("slot in other zone converts to same instant in UTC", func(t *testing.T) {
+		slot := CalendarSlot{
+			ID:    "slot-456",
+			Start: time.Date(2025, 1, 8, 17, 0, 0, 0, moscow),
+			End:   time.Date(2025, 1, 8, 18, 0, 0, 0, moscow),
+			Type:  models.SlotTypeBooking,
+		}
+
+		back := CalendarSlotFromModel(slot.ToModel())
+		assert.True(t, back.Start.Equal(slot.Start))
+		assert.True(t, back.End.Equal(slot.End))
+		assert.Equal(t, time.UTC, back.Start.Location())
+	})
+
+	t.Run("booking round trip", func(t *testing.T) {
+		booking := CalendarBooking{
+			ID:          "booking-123",
+			BookingID:   "booking-id-123",
+			EventSlotID: "slot-123",
+			Start:       time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC),
+			End:         time.Date(2025, 1, 8, 14, 30, 0, 0, time.UTC),
+			ProjectName: "go-concurrency",
+		}
+
+		model := booking.ToModel()
+		assert.Equal(t, "booking-id-123", model.BookingID)
+		assert.Equal(t, booking.Start.Unix(), model.StartTime)
+		assert.Equal(t, booking, CalendarBookingFromModel(model))
+		assert.Equal(t, model, CalendarBookingFromModel(model).ToModel())
+	})
+}
+
+func TestFindEarliestFreeSlot(t *testing.T) {
+	base := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+	slot := func(id, slotType string, startMin, durMin int) CalendarSlot {
+		start := base.Add(time.Duration(startMin) * time.Minute)
+		return CalendarSlot{ID: id, Start: start, End: start.Add(time.Duration(durMin) * time.Minute), Type: slotType}
+	}
+
+	tests := []struct {
+		name       string
+		slots      []CalendarSlot
+		after      time.Time
+		minDur     time.Duration
+		expectedID string
+	}{
+		{
+			name:   "no match",
+			slots:  []CalendarSlot{slot("booking", models.SlotTypeBooking, 60, 60), slot("past", models.SlotTypeFreeTime, -60, 60)},
+			after:  base,
+			minDur: 30 * time.Minute,
+		},
+		{
+			name:       "too-short free slot skipped",
+			slots:      []CalendarSlot{slot("short", models.SlotTypeFreeTime, 0, 15), slot("long", models.SlotTypeFreeTime, 60, 45)},
+			after:      base,
+			minDur:     30 * time.Minute,
+			expectedID: "long",
+		},
+		{
+			name:       "earliest start wins regardless of order",
+			slots:      []CalendarSlot{slot("later", models.SlotTypeFreeTime, 120, 60), slot("earlier", models.SlotTypeFreeTime, 30, 60), slot("booked", models.SlotTypeBooking, 0, 60)},
+			after:      base,
+			minDur:     30 * time.Minute,
+			expectedID: "earlier",
+		},
+		{
+			name:       "tie keeps first earliest slot",
+			slots:      []CalendarSlot{slot("first", models.SlotTypeFreeTime, 30, 30), slot("second", models.SlotTypeFreeTime, 30, 90)},
+			after:      base,
+			minDur:     30 * time.Minute,
+			expectedID: "first",
+		},
+		{
+			name:       "slot starting exactly at after is accepted",
+			slots:      []CalendarSlot{slot("exact", models.SlotTypeFreeTime, 0, 30)},
+			after:      base,
+			minDur:     30 * time.Minute,
+			expectedID: "exact",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, ok := FindEarliestFreeSlot(tt.slots, tt.after, tt.minDur)
+			if tt.expectedID == "" {
+				assert.False(t, ok)
+				assert.Nil(t, found)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedID, found.ID)
+		})
+	}
+}
+
+func TestNewS21ClientFromUserTokens(t *testing.T) {
+	t.Run("valid tokens are used without refresh", func(t *testing.T) {
+		tokens := &models.UserTokens{
+			AccessToken:  "access_token",
+			RefreshToken: "refresh_token",
+			IssueTime:    time.Now().Unix(),
+			ExpiryTime:   time.Now().Add(time.Hour).Unix(),
+		}
+
+		client := NewS21ClientFromUserTokens(tokens, "")
+		require.NotNil(t, client)
+		require.NotNil(t, client.auth)
+		client.SetSchoolID("school123")
+		client.SetContextHeaders(&s21client.ContextHeaders{XEDUSchoolID: "school123"})
+
+		creds, err := client.auth.GetAuthCredentials(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "access_token", creds.Token)
+		assert.Equal(t, tokens.ExpiryTime, client.auth.token.ExpiryTime)
+		assert.Equal(t, tokens.IssueTime, client.auth.token.IssueTime)
+		assert.Equal(t, "school21", client.auth.clientID)
+	})
+
+	t.Run("nil tokens", func(t *testing.T) {
+		assert.Nil(t, NewS21ClientFromUserTokens(nil, ""))
+	})
+}
+
+func TestResolveClientID(t *testing.T) {
+	t.Run("explicit value wins", func(t *testing.T) {
+		t.Setenv(clientIDEnvVar, "env-client")
+		assert.Equal(t, "explicit-client", resolveClientID("explicit-client"))
+	})
+
+	t.Run("env fallback", func(t *testing.T) {
+		t.Setenv(clientIDEnvVar, "env-client")
+		assert.Equal(t, "env-client", resolveClientID(""))
+
+		client := NewS21Client("access_token", "refresh_token", "")
+		assert.Equal(t, "env-client", client.auth.clientID)
+	})
+
+	t.Run("hardcoded default", func(t *testing.T) {
+		t.Setenv(clientIDEnvVar, "")
+		assert.Equal(t, "school21", resolveClientID(""))
+	})
+}
+
+func TestAuthenticate_ErrorClassification(t *testing.T) {
+	stubTokenEndpoint := func(t *testing.T, url string) {
+		originalURL := tokenEndpointURL
+		tokenEndpointURL = url
+		t.Cleanup(func() { tokenEndpointURL = originalURL })
+	}
+
+	t.Run("401 maps to invalid credentials", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"Invalid user credentials"}`))
+		}))
+		t.Cleanup(server.Close)
+		stubTokenEndpoint(t, server.URL)
+
+		tokens, err := Authenticate(context.Background(), "user", "wrong")
+
+		assert.Nil(t, tokens)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		assert.NotErrorIs(t, err, ErrAuthUnavailable)
+		assert.Contains(t, err.Error(), "Invalid user credentials")
+	})
+
+	t.Run("5xx maps to unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		t.Cleanup(server.Close)
+		stubTokenEndpoint(t, server.URL)
+
+		_, err := Authenticate(context.Background(), "user", "password")
+
+		assert.ErrorIs(t, err, ErrAuthUnavailable)
+	})
+
+	t.Run("connection error maps to unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		closedURL := server.URL
+		server.Close()
+		stubTokenEndpoint(t, closedURL)
+
+		_, err := Authenticate(context.Background(), "user", "password")
+
+		assert.ErrorIs(t, err, ErrAuthUnavailable)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("success returns tokens", func(t *testing.T) {
+		var form url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			form = r.PostForm
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","expires_in":300,"token_type":"Bearer"}`))
+		}))
+		t.Cleanup(server.Close)
+		stubTokenEndpoint(t, server.URL)
+
+		tokens, err := Authenticate(context.Background(), "user", "password")
+
+		require.NoError(t, err)
+		assert.Equal(t, "access", tokens.AccessToken)
+		assert.Equal(t, "refresh", tokens.RefreshToken)
+		assert.Equal(t, int64(300), tokens.ExpiresIn)
+		assert.Equal(t, "password", form.Get("grant_type"))
+		assert.Equal(t, "user", form.Get("username"))
+	})
+
+	t.Run("malformed success body is a parse error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html>maintenance</html>`))
+		}))
+		t.Cleanup(server.Close)
+		stubTokenEndpoint(t, server.URL)
+
+		tokens, err := Authenticate(context.Background(), "user", "password")
+
+		assert.Nil(t, tokens)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse token response")
+		var syntaxErr *json.SyntaxError
+		assert.ErrorAs(t, err, &syntaxErr)
+	})
+}
+
+func TestFetchNotificationsForUsers(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			prev := maxInFlight.Load()
+			if current <= prev || maxInFlight.CompareAndSwap(prev, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		login := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer token-")
+		w.Header().Set("Content-Type", "application/json")
+		if login == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"s21Notification":{"getS21Notifications":{"notifications":[` +
+			`{"id":"notif-` + login + `","relatedObjectType":"BOOKING","relatedObjectId":"slot-1","message":"m","time":"2025-01-08T14:00:00Z","wasRead":false,"groupName":"g"}` +
+			`],"totalCount":1,"groupNames":["g"]}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	originalURL := s21client.S21GqlUrl
+	s21client.S21GqlUrl = server.URL
+	t.Cleanup(func() { s21client.S21GqlUrl = originalURL })
+
+	clientFor := func(login string) *S21Client {
+		auth := &S21AuthProvider{
+			token: s21auth.Token{
+				AccessToken:  "token-" + login,
+				RefreshToken: "refresh_token",
+				IssueTime:    time.Now().Unix(),
+				ExpiryTime:   time.Now().Add(time.Hour).Unix(),
+			},
+			schoolID:       "school123",
+			contextHeaders: &s21client.ContextHeaders{XEDUSchoolID: "school123"},
+			clientID:       "school21",
+		}
+		return &S21Client{client: s21client.New(auth), auth: auth}
+	}
+
+	clients := map[string]*S21Client{}
+	for i := 0; i < 8; i++ {
+		login := fmt.Sprintf("user%d", i)
+		clients[login] = clientFor(login)
+	}
+	clients["broken"] = clientFor("broken")
+
+	results, failures := FetchNotificationsForUsers(context.Background(), clients, 3)
+
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+	assert.Len(t, results, 8)
+	for i := 0; i < 8; i++ {
+		login := fmt.Sprintf("user%d", i)
+		require.Len(t, results[login], 1)
+		assert.Equal(t, "notif-"+login, results[login][0].ID)
+	}
+	require.Len(t, failures, 1)
+	assert.Error(t, failures["broken"])
+
+	t.Run("cancelled context fails every user", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results, failures := FetchNotificationsForUsers(ctx, clients, 2)
+
+		assert.Empty(t, results)
+		assert.Len(t, failures, len(clients))
+		for _, err := range failures {
+			assert.ErrorIs(t, err, context.Canceled)
+		}
+	})
+}
+
+func TestChangeEventSlotSafe(t *testing.T) {
+	base := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+	existing := []CalendarSlot{
+		{ID: "slot-1", Start: base, End: base.Add(time.Hour), Type: models.SlotTypeFreeTime},
+		{ID: "slot-2", Start: base.Add(2 * time.Hour), End: base.Add(3 * time.Hour), Type: models.SlotTypeBooking},
+	}
+
+	var calls atomic.Int32
+	client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+
+	t.Run("conflicting move is rejected before the network call", func(t *testing.T) {
+		calls.Store(0)
+
+		err := client.ChangeEventSlotSafe(context.Background(), "slot-1", base.Add(90*time.Minute), base.Add(150*time.Minute), existing)
+
+		assert.ErrorIs(t, err, ErrSlotConflict)
+		assert.Contains(t, err.Error(), "slot-2")
+		assert.Equal(t, int32(0), calls.Load())
+	})
+
+	t.Run("clear move is sent", func(t *testing.T) {
+		calls.Store(0)
+
+		err := client.ChangeEventSlotSafe(context.Background(), "slot-1", base.Add(30*time.Minute), base.Add(2*time.Hour), existing)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("inverted range is rejected", func(t *testing.T) {
+		calls.Store(0)
+
+		err := client.ChangeEventSlotSafe(context.Background(), "slot-1", base.Add(time.Hour), base, existing)
+
+		assert.Error(t, err)
+		assert.Equal(t, int32(0), calls.Load())
+	})
+}
+
+func TestCalendarJSONWireFormat(t *testing.T) {
+	at := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+
+	t.Run("slot", func(t *testing.T) {
+		slot := CalendarSlot{ID: "slot-1", Start: at, End: at.Add(time.Hour), Type: models.SlotTypeFreeTime}
+
+		data, err := json.Marshal(slot)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"slot-1","start":"2025-01-08T14:00:00Z","end":"2025-01-08T15:00:00Z","type":"FREE_TIME"}`, string(data))
+
+		var decoded CalendarSlot
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, slot, decoded)
+	})
+
+	t.Run("booking", func(t *testing.T) {
+		booking := CalendarBooking{
+			ID:          "booking-1",
+			BookingID:   "booking-1",
+			EventSlotID: "slot-1",
+			Start:       at,
+			End:         at.Add(30 * time.Minute),
+			ProjectName: "C2_SimpleBashUtils",
+		}
+
+		data, err := json.Marshal(booking)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"booking-1","booking_id":"booking-1","event_slot_id":"slot-1",`+
+			`"start":"2025-01-08T14:00:00Z","end":"2025-01-08T14:30:00Z","project_name":"C2_SimpleBashUtils"}`, string(data))
+
+		var decoded CalendarBooking
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, booking, decoded)
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		notification := Notification{
+			ID:                "notif-1",
+			RelatedObjectType: NotificationTypeBooking,
+			RelatedObjectID:   "slot-1",
+			Message:           "Review requested",
+			Time:              at,
+			WasRead:           true,
+			GroupName:         "Reviews",
+		}
+
+		data, err := json.Marshal(notification)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"id":"notif-1","related_object_type":"BOOKING","related_object_id":"slot-1",`+
+			`"message":"Review requested","time":"2025-01-08T14:00:00Z","was_read":true,"group_name":"Reviews"}`, string(data))
+
+		var decoded Notification
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, notification, decoded)
+	})
+}
+
+func TestSortByStart(t *testing.T) {
+	base := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+
+	t.Run("slots", func(t *testing.T) {
+		slots := []CalendarSlot{
+			{ID: "late", Start: base.Add(2 * time.Hour)},
+			{ID: "tie-a", Start: base},
+			{ID: "early", Start: base.Add(-time.Hour)},
+			{ID: "tie-b", Start: base},
+		}
+
+		SortSlotsByStart(slots)
+
+		ids := make([]string, len(slots))
+		for i, slot := range slots {
+			ids[i] = slot.ID
+		}
+		assert.Equal(t, []string{"early", "tie-a", "tie-b", "late"}, ids)
+	})
+
+	t.Run("bookings", func(t *testing.T) {
+		bookings := []CalendarBooking{
+			{ID: "tie-a", Start: base},
+			{ID: "late", Start: base.Add(time.Hour)},
+			{ID: "tie-b", Start: base},
+			{ID: "early", Start: base.Add(-30 * time.Minute)},
+		}
+
+		SortBookingsByStart(bookings)
+
+		ids := make([]string, len(bookings))
+		for i, booking := range bookings {
+			ids[i] = booking.ID
+		}
+		assert.Equal(t, []string{"early", "tie-a", "tie-b", "late"}, ids)
+	})
+}
+
+func TestS21ClientTracing(t *testing.T) {
+	newTracedClient := func(t *testing.T, handler http.HandlerFunc) (*S21Client, *tracetest.SpanRecorder) {
+		t.Helper()
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+		client := newStubbedS21Client(t, handler)
+		client.SetTracer(provider.Tracer("s21client-test"))
+		return client, recorder
+	}
+
+	t.Run("GetCalendarEvents creates a span", func(t *testing.T) {
+		client, recorder := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"data":{"calendarEventS21":{"getMyCalendarEvents":[]}}}`)
+		})
+
+		_, err := client.GetCalendarEvents(context.Background(), time.Now(), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "s21.CalendarGetEvents", spans[0].Name())
+		assert.Contains(t, spans[0].Attributes(), attribute.String("s21.school_id", "school123"))
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	})
+
+	t.Run("records request errors", func(t *testing.T) {
+		client, recorder := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		_, err := client.GetCalendarEvents(context.Background(), time.Now(), time.Now().Add(time.Hour))
+		require.Error(t, err)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+		assert.NotEmpty(t, spans[0].Events())
+	})
+
+	t.Run("starting a span does not wait for a token refresh", func(t *testing.T) {
+		client, recorder := newTracedClient(t, func(w http.ResponseWriter, r *http.Request) {})
+
+		// Holding mu is what GetAuthCredentials does for the whole refresh
+		client.auth.mu.Lock()
+		defer client.auth.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, endSpan := client.startSpan(context.Background(), "CalendarGetEvents")
+			endSpan(nil)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("startSpan blocked on the token refresh lock")
+		}
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Contains(t, spans[0].Attributes(), attribute.String("s21.school_id", "school123"))
+	})
+
+	t.Run("no tracer creates no spans", func(t *testing.T) {
+		// A recording global provider would catch spans started through otel.Tracer
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(provider)
+		t.Cleanup(func() {
+			otel.SetTracerProvider(previous)
+			_ = provider.Shutdown(context.Background())
+		})
+
+		client := newStubbedS21Client(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"data":{"calendarEventS21":{"getMyCalendarEvents":[]}}}`)
+		})
+
+		_, err := client.GetCalendarEvents(context.Background(), time.Now(), time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, recorder.Started())
+		assert.Empty(t, recorder.Ended())
+	})
+}
+
+func TestReconcileSlots(t *testing.T) {
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	slot := func(id string, startHour, endHour int) CalendarSlot {
+		return CalendarSlot{
+			ID:    id,
+			Start: base.Add(time.Duration(startHour) * time.Hour),
+			End:   base.Add(time.Duration(endHour) * time.Hour),
+			Type:  models.SlotTypeFreeTime,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		desired    []CalendarSlot
+		actual     []CalendarSlot
+		wantCreate []CalendarSlot
+		wantDelete []CalendarSlot
+		wantChange [][2]CalendarSlot
+	}{
+		{
+			name:    "in sync",
+			desired: []CalendarSlot{slot("a", 0, 1), slot("b", 2, 3)},
+			actual:  []CalendarSlot{slot("a", 0, 1), slot("b", 2, 3)},
+		},
+		{
+			name:       "additions",
+			desired:    []CalendarSlot{slot("a", 0, 1), slot("", 4, 5)},
+			actual:     []CalendarSlot{slot("a", 0, 1)},
+			wantCreate: []CalendarSlot{slot("", 4, 5)},
+		},
+		{
+			name:       "deletions",
+			desired:    []CalendarSlot{slot("a", 0, 1)},
+			actual:     []CalendarSlot{slot("a", 0, 1), slot("b", 2, 3)},
+			wantDelete: []CalendarSlot{slot("b", 2, 3)},
+		},
+		{
+			name:       "time changes",
+			desired:    []CalendarSlot{slot("a", 1, 2), slot("b", 2, 3)},
+			actual:     []CalendarSlot{slot("a", 0, 1), slot("b", 2, 3)},
+			wantChange: [][2]CalendarSlot{{slot("a", 0, 1), slot("a", 1, 2)}},
+		},
+		{
+			name:    "new slot matching an existing window is kept",
+			desired: []CalendarSlot{slot("", 2, 3)},
+			actual:  []CalendarSlot{slot("b", 2, 3)},
+		},
+		{
+			name:       "mixed",
+			desired:    []CalendarSlot{slot("a", 5, 6), slot("", 7, 8)},
+			actual:     []CalendarSlot{slot("a", 0, 1), slot("c", 3, 4)},
+			wantCreate: []CalendarSlot{slot("", 7, 8)},
+			wantDelete: []CalendarSlot{slot("c", 3, 4)},
+			wantChange: [][2]CalendarSlot{{slot("a", 0, 1), slot("a", 5, 6)}},
+		},
+		{
+			name:       "empty desired deletes everything",
+			actual:     []CalendarSlot{slot("a", 0, 1)},
+			wantDelete: []CalendarSlot{slot("a", 0, 1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toCreate, toDelete, toChange := ReconcileSlots(tt.desired, tt.actual)
+
+			assert.Equal(t, tt.wantCreate, toCreate)
+			assert.Equal(t, tt.wantDelete, toDelete)
+			assert.Equal(t, tt.wantChange, toChange)
+		})
+	}
+}
@@ -1,5 +1,18 @@
 package models
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
 // Review request statuses
 const (
 	StatusUnknownProjectReview        = "UNKNOWN_PROJECT_REVIEW"
@@ -62,6 +75,56 @@ func IsFinalStatus(status string) bool {
 	}
 }
 
+// FinalStatuses returns all final review request statuses
+func FinalStatuses() []string {
+	return []string{
+		StatusApproved,
+		StatusCancelled,
+		StatusAutoCancelled,
+		StatusAutoCancelledNotWhitelisted,
+	}
+}
+
+// statusTransitions lists the allowed next statuses for each status
+// Any intermediate status may be CANCELLED when the booking disappears upstream
+// Final statuses have no outgoing edges
+var statusTransitions = map[string][]string{
+	StatusUnknownProjectReview: {StatusKnownProjectReview, StatusCancelled},
+	StatusKnownProjectReview:   {StatusWhitelisted, StatusNotWhitelisted, StatusCancelled},
+	StatusWhitelisted:          {StatusNeedToApprove, StatusWaitingForApprove, StatusApproved, StatusCancelled},
+	StatusNotWhitelisted:       {StatusWaitingForAutoCancel, StatusAutoCancelledNotWhitelisted, StatusCancelled},
+	StatusWaitingForAutoCancel: {StatusAutoCancelledNotWhitelisted, StatusCancelled},
+	StatusNeedToApprove:        {StatusWaitingForApprove, StatusCancelled},
+	StatusWaitingForApprove:    {StatusApproved, StatusCancelled, StatusAutoCancelled},
+}
+
+// CanTransition reports whether a review request may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTransition returns an error describing why a status change is not allowed
+func ValidateTransition(from, to string) error {
+	if !IsValidStatus(from) {
+		return fmt.Errorf("%s: unknown current status %q", ErrInvalidTransition, from)
+	}
+	if !IsValidStatus(to) {
+		return fmt.Errorf("%s: unknown target status %q", ErrInvalidTransition, to)
+	}
+	if IsFinalStatus(from) {
+		return fmt.Errorf("%s: %s is final and cannot change to %s", ErrInvalidTransition, from, to)
+	}
+	if !CanTransition(from, to) {
+		return fmt.Errorf("%s: %s cannot change to %s", ErrInvalidTransition, from, to)
+	}
+	return nil
+}
+
 // User represents a reviewer in the users table
 type User struct {
 	ReviewerLogin     string  `db:"reviewer_login"`
@@ -72,6 +135,33 @@ type User struct {
 	LastAuthFailureAt *uint32 `db:"last_auth_failure_at"`
 }
 
+// HasStaleAuth reports whether an active user keeps failing authentication since before cutoff
+// The user must have failed after their last success (or never succeeded), and that last
+// success (or account creation) must be older than cutoff
+func (u *User) HasStaleAuth(cutoff int64) bool {
+	if u.Status != UserStatusActive || u.LastAuthFailureAt == nil {
+		return false
+	}
+
+	lastGood := int64(u.CreatedAt)
+	if u.LastAuthSuccessAt != nil {
+		if *u.LastAuthSuccessAt >= *u.LastAuthFailureAt {
+			return false
+		}
+		lastGood = int64(*u.LastAuthSuccessAt)
+	}
+
+	return lastGood < cutoff
+}
+
+// CurrentUser is the authenticated s21 platform user
+type CurrentUser struct {
+	ID              string
+	Login           string
+	FullName        string
+	SchoolStudentID string // currentSchoolStudentId, the user's student ID in their current school
+}
+
 // UserSettings represents per-user configuration
 type UserSettings struct {
 	ReviewerLogin                  string `db:"reviewer_login"`
@@ -84,18 +174,93 @@ type UserSettings struct {
 	CleanupDurationsMinutes        int32  `db:"cleanup_durations_minutes"`
 }
 
+// Allowed range for minute-based user settings
+const (
+	MinSettingMinutes = 0
+	MaxSettingMinutes = 240
+)
+
+// builtinUserSettings are the defaults used when no override file has been loaded
+var builtinUserSettings = UserSettings{
+	ResponseDeadlineShiftMinutes:   20,
+	NonWhitelistCancelDelayMinutes: 5,
+	NotifyWhitelistTimeout:         true,
+	NotifyNonWhitelistCancel:       true,
+	SlotShiftThresholdMinutes:      25,
+	SlotShiftDurationMinutes:       15,
+	CleanupDurationsMinutes:        15,
+}
+
+// loadedUserSettings holds defaults loaded by LoadDefaultUserSettings (nil means builtin)
+var loadedUserSettings atomic.Pointer[UserSettings]
+
 // DefaultUserSettings returns default user settings
 func DefaultUserSettings(reviewerLogin string) *UserSettings {
-	return &UserSettings{
-		ReviewerLogin:                  reviewerLogin,
-		ResponseDeadlineShiftMinutes:   20,
-		NonWhitelistCancelDelayMinutes: 5,
-		NotifyWhitelistTimeout:         true,
-		NotifyNonWhitelistCancel:       true,
-		SlotShiftThresholdMinutes:      25,
-		SlotShiftDurationMinutes:       15,
-		CleanupDurationsMinutes:        15,
+	settings := builtinUserSettings
+	if loaded := loadedUserSettings.Load(); loaded != nil {
+		settings = *loaded
+	}
+	settings.ReviewerLogin = reviewerLogin
+	return &settings
+}
+
+// userSettingsDefaultsFile is the JSON layout read by LoadDefaultUserSettings; absent keys keep the builtin value
+type userSettingsDefaultsFile struct {
+	ResponseDeadlineShiftMinutes   *int32 `json:"response_deadline_shift_minutes"`
+	NonWhitelistCancelDelayMinutes *int32 `json:"non_whitelist_cancel_delay_minutes"`
+	NotifyWhitelistTimeout         *bool  `json:"notify_whitelist_timeout"`
+	NotifyNonWhitelistCancel       *bool  `json:"notify_non_whitelist_cancel"`
+	SlotShiftThresholdMinutes      *int32 `json:"slot_shift_threshold_minutes"`
+	SlotShiftDurationMinutes       *int32 `json:"slot_shift_duration_minutes"`
+	CleanupDurationsMinutes        *int32 `json:"cleanup_durations_minutes"`
+}
+
+// LoadDefaultUserSettings reads org-wide defaults from a JSON file and uses them in DefaultUserSettings
+// Keys missing from the file keep the builtin defaults; on any error the current defaults are unchanged
+func LoadDefaultUserSettings(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read default user settings: %w", err)
+	}
+
+	var file userSettingsDefaultsFile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("failed to parse default user settings %s: %w", path, err)
+	}
+
+	settings := builtinUserSettings
+	minutes := []struct {
+		name string
+		src  *int32
+		dst  *int32
+	}{
+		{"response_deadline_shift_minutes", file.ResponseDeadlineShiftMinutes, &settings.ResponseDeadlineShiftMinutes},
+		{"non_whitelist_cancel_delay_minutes", file.NonWhitelistCancelDelayMinutes, &settings.NonWhitelistCancelDelayMinutes},
+		{"slot_shift_threshold_minutes", file.SlotShiftThresholdMinutes, &settings.SlotShiftThresholdMinutes},
+		{"slot_shift_duration_minutes", file.SlotShiftDurationMinutes, &settings.SlotShiftDurationMinutes},
+		{"cleanup_durations_minutes", file.CleanupDurationsMinutes, &settings.CleanupDurationsMinutes},
+	}
+	for _, m := range minutes {
+		if m.src == nil {
+			continue
+		}
+		if *m.src < MinSettingMinutes || *m.src > MaxSettingMinutes {
+			return fmt.Errorf("invalid default user settings %s: %s must be between %d and %d minutes, got %d",
+				path, m.name, MinSettingMinutes, MaxSettingMinutes, *m.src)
+		}
+		*m.dst = *m.src
+	}
+	if file.NotifyWhitelistTimeout != nil {
+		settings.NotifyWhitelistTimeout = *file.NotifyWhitelistTimeout
+	}
+	if file.NotifyNonWhitelistCancel != nil {
+		settings.NotifyNonWhitelistCancel = *file.NotifyNonWhitelistCancel
 	}
+
+	loadedUserSettings.Store(&settings)
+	return nil
 }
 
 // ProjectFamily represents a project in the project_families table
@@ -129,6 +294,144 @@ type ReviewRequest struct {
 	DecidedAt            *uint32 `db:"decided_at"`
 }
 
+// NewReviewRequestID returns a new review request ID as a canonical lowercase UUIDv4
+func NewReviewRequestID() string {
+	return uuid.New().String()
+}
+
+// NewReviewRequest creates a review request with a generated ID, the initial status and CreatedAt set to now
+// Optional fields are left nil
+func NewReviewRequest(reviewerLogin, calendarSlotID string, reviewStart int64) *ReviewRequest {
+	return &ReviewRequest{
+		ID:              NewReviewRequestID(),
+		ReviewerLogin:   reviewerLogin,
+		ReviewStartTime: uint32(reviewStart),
+		CalendarSlotID:  calendarSlotID,
+		Status:          StatusUnknownProjectReview,
+		CreatedAt:       uint32(time.Now().Unix()),
+	}
+}
+
+// reviewRequestJSON is the JSON representation of ReviewRequest with RFC3339 timestamps
+type reviewRequestJSON struct {
+	ID                   string     `json:"id"`
+	ReviewerLogin        string     `json:"reviewer_login"`
+	NotificationID       *string    `json:"notification_id"`
+	ProjectName          *string    `json:"project_name"`
+	FamilyLabel          *string    `json:"family_label"`
+	ReviewStartTime      time.Time  `json:"review_start_time"`
+	CalendarSlotID       string     `json:"calendar_slot_id"`
+	BookingID            string     `json:"booking_id"`
+	DecisionDeadline     *time.Time `json:"decision_deadline"`
+	NonWhitelistCancelAt *time.Time `json:"non_whitelist_cancel_at"`
+	TelegramMessageID    *string    `json:"telegram_message_id"`
+	Status               string     `json:"status"`
+	CreatedAt            time.Time  `json:"created_at"`
+	DecidedAt            *time.Time `json:"decided_at"`
+}
+
+// MarshalJSON renders timestamps as RFC3339 strings and nil optional timestamps as null
+func (r ReviewRequest) MarshalJSON() ([]byte, error) {
+	return json.Marshal(reviewRequestJSON{
+		ID:                   r.ID,
+		ReviewerLogin:        r.ReviewerLogin,
+		NotificationID:       r.NotificationID,
+		ProjectName:          r.ProjectName,
+		FamilyLabel:          r.FamilyLabel,
+		ReviewStartTime:      unixToTime(r.ReviewStartTime),
+		CalendarSlotID:       r.CalendarSlotID,
+		BookingID:            r.BookingID,
+		DecisionDeadline:     optionalUnixToTime(r.DecisionDeadline),
+		NonWhitelistCancelAt: optionalUnixToTime(r.NonWhitelistCancelAt),
+		TelegramMessageID:    r.TelegramMessageID,
+		Status:               r.Status,
+		CreatedAt:            unixToTime(r.CreatedAt),
+		DecidedAt:            optionalUnixToTime(r.DecidedAt),
+	})
+}
+
+// UnmarshalJSON parses RFC3339 timestamps back into unix seconds
+func (r *ReviewRequest) UnmarshalJSON(data []byte) error {
+	var v reviewRequestJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	reviewStartTime, err := timeToUnix(v.ReviewStartTime)
+	if err != nil {
+		return fmt.Errorf("review_start_time: %w", err)
+	}
+	createdAt, err := timeToUnix(v.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("created_at: %w", err)
+	}
+	decisionDeadline, err := optionalTimeToUnix(v.DecisionDeadline)
+	if err != nil {
+		return fmt.Errorf("decision_deadline: %w", err)
+	}
+	nonWhitelistCancelAt, err := optionalTimeToUnix(v.NonWhitelistCancelAt)
+	if err != nil {
+		return fmt.Errorf("non_whitelist_cancel_at: %w", err)
+	}
+	decidedAt, err := optionalTimeToUnix(v.DecidedAt)
+	if err != nil {
+		return fmt.Errorf("decided_at: %w", err)
+	}
+
+	*r = ReviewRequest{
+		ID:                   v.ID,
+		ReviewerLogin:        v.ReviewerLogin,
+		NotificationID:       v.NotificationID,
+		ProjectName:          v.ProjectName,
+		FamilyLabel:          v.FamilyLabel,
+		ReviewStartTime:      reviewStartTime,
+		CalendarSlotID:       v.CalendarSlotID,
+		BookingID:            v.BookingID,
+		DecisionDeadline:     decisionDeadline,
+		NonWhitelistCancelAt: nonWhitelistCancelAt,
+		TelegramMessageID:    v.TelegramMessageID,
+		Status:               v.Status,
+		CreatedAt:            createdAt,
+		DecidedAt:            decidedAt,
+	}
+	return nil
+}
+
+// unixToTime converts unix seconds to a UTC time
+func unixToTime(ts uint32) time.Time {
+	return time.Unix(int64(ts), 0).UTC()
+}
+
+// optionalUnixToTime converts optional unix seconds to an optional UTC time
+func optionalUnixToTime(ts *uint32) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := unixToTime(*ts)
+	return &t
+}
+
+// timeToUnix converts a time to unix seconds, rejecting values outside the uint32 range
+func timeToUnix(t time.Time) (uint32, error) {
+	sec := t.Unix()
+	if sec < 0 || sec > math.MaxUint32 {
+		return 0, fmt.Errorf("timestamp %s out of range", t.Format(time.RFC3339))
+	}
+	return uint32(sec), nil
+}
+
+// optionalTimeToUnix converts an optional time to optional unix seconds
+func optionalTimeToUnix(t *time.Time) (*uint32, error) {
+	if t == nil {
+		return nil, nil
+	}
+	ts, err := timeToUnix(*t)
+	if err != nil {
+		return nil, err
+	}
+	return &ts, nil
+}
+
 // CalendarSlot represents a time slot from the calendar API
 type CalendarSlot struct {
 	ID    string
@@ -137,9 +440,23 @@ type CalendarSlot struct {
 	Type  string
 }
 
+// DurationMinutes returns the slot length in whole minutes, or 0 if End is not after Start
+func (s CalendarSlot) DurationMinutes() int {
+	if s.End <= s.Start {
+		return 0
+	}
+	return int((s.End - s.Start) / 60)
+}
+
+// IsValid checks that the slot has an ID, a known type and a positive length
+func (s CalendarSlot) IsValid() bool {
+	return s.ID != "" && IsValidSlotType(s.Type) && s.End > s.Start
+}
+
 // CalendarBooking represents a booking from the calendar API
 type CalendarBooking struct {
 	ID          string
+	BookingID   string
 	EventSlotID string
 	StartTime   int64
 	EndTime     int64
@@ -169,6 +486,51 @@ type UserTokens struct {
 	ExpiryTime    int64  `db:"expiry_time" json:"expiry_time"` // Unix timestamp when token expires
 }
 
+// SecretString is a string that prints redacted; use Value to read the real contents
+type SecretString string
+
+// redactedSecret replaces a non-empty secret in formatted output
+const redactedSecret = "[REDACTED]"
+
+// Value returns the unredacted secret
+func (s SecretString) Value() string {
+	return string(s)
+}
+
+// String redacts the secret, keeping an empty secret visibly empty
+func (s SecretString) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+// GoString redacts the secret in %#v output
+func (s SecretString) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+// String formats the tokens like %+v with the token values redacted
+// The AccessToken and RefreshToken fields still hold the real values
+func (t UserTokens) String() string {
+	return fmt.Sprintf("{ReviewerLogin:%s AccessToken:%s RefreshToken:%s CreatedAt:%d UpdatedAt:%d IssueTime:%d ExpiryTime:%d}",
+		t.ReviewerLogin, SecretString(t.AccessToken), SecretString(t.RefreshToken), t.CreatedAt, t.UpdatedAt, t.IssueTime, t.ExpiryTime)
+}
+
+// GoString formats the tokens like %#v with the token values redacted
+func (t UserTokens) GoString() string {
+	return fmt.Sprintf("models.UserTokens{ReviewerLogin:%q, AccessToken:%#v, RefreshToken:%#v, CreatedAt:%d, UpdatedAt:%d, IssueTime:%d, ExpiryTime:%d}",
+		t.ReviewerLogin, SecretString(t.AccessToken), SecretString(t.RefreshToken), t.CreatedAt, t.UpdatedAt, t.IssueTime, t.ExpiryTime)
+}
+
+// IsExpired reports whether the access token is missing or expires within buffer
+func (t *UserTokens) IsExpired(buffer time.Duration) bool {
+	if t.AccessToken == "" || t.ExpiryTime == 0 {
+		return true
+	}
+	return time.Now().Add(buffer).Unix() >= t.ExpiryTime
+}
+
 // TokenResponse represents the authentication response from s21 platform
 type TokenResponse struct {
 	Error            string `json:"error"`
@@ -181,12 +543,49 @@ type TokenResponse struct {
 	ExpiryTime       int64  `json:"expiry_time"` // Unix timestamp when token expires
 }
 
+// String formats the response like %+v with the token values redacted
+// The AccessToken and RefreshToken fields still hold the real values
+func (tr TokenResponse) String() string {
+	return fmt.Sprintf("{Error:%s AccessToken:%s ExpiresIn:%d RefreshExpiresIn:%d RefreshToken:%s TokenType:%s IssueTime:%d ExpiryTime:%d}",
+		tr.Error, SecretString(tr.AccessToken), tr.ExpiresIn, tr.RefreshExpiresIn, SecretString(tr.RefreshToken), tr.TokenType, tr.IssueTime, tr.ExpiryTime)
+}
+
+// GoString formats the response like %#v with the token values redacted
+func (tr TokenResponse) GoString() string {
+	return fmt.Sprintf("models.TokenResponse{Error:%q, AccessToken:%#v, ExpiresIn:%d, RefreshExpiresIn:%d, RefreshToken:%#v, TokenType:%q, IssueTime:%d, ExpiryTime:%d}",
+		tr.Error, SecretString(tr.AccessToken), tr.ExpiresIn, tr.RefreshExpiresIn, SecretString(tr.RefreshToken), tr.TokenType, tr.IssueTime, tr.ExpiryTime)
+}
+
+// ToUserTokens maps the response into UserTokens for storage, stamping CreatedAt and UpdatedAt with now
+// A zero IssueTime defaults to now and a zero ExpiryTime is derived from IssueTime + ExpiresIn
+func (tr *TokenResponse) ToUserTokens(reviewerLogin string, now int64) UserTokens {
+	issueTime := tr.IssueTime
+	if issueTime == 0 {
+		issueTime = now
+	}
+	expiryTime := tr.ExpiryTime
+	if expiryTime == 0 && tr.ExpiresIn > 0 {
+		expiryTime = issueTime + tr.ExpiresIn
+	}
+
+	return UserTokens{
+		ReviewerLogin: reviewerLogin,
+		AccessToken:   tr.AccessToken,
+		RefreshToken:  tr.RefreshToken,
+		CreatedAt:     uint32(now),
+		UpdatedAt:     uint32(now),
+		IssueTime:     issueTime,
+		ExpiryTime:    expiryTime,
+	}
+}
+
 // Validation errors
 var (
 	ErrInvalidStatus     = "invalid review status"
 	ErrInvalidEntryType  = "invalid whitelist entry type"
 	ErrInvalidUserStatus = "invalid user status"
 	ErrInvalidReviewID   = "invalid review request ID"
+	ErrInvalidTransition = "invalid status transition"
 )
 
 // IsValidStatus checks if a status string is valid
@@ -209,12 +608,156 @@ func IsValidStatus(status string) bool {
 	}
 }
 
+// IsValidReviewID checks if id is a canonical UUID string (xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx)
+func IsValidReviewID(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
 // IsValidEntryType checks if an entry type is valid
 func IsValidEntryType(entryType string) bool {
 	return entryType == EntryTypeFamily || entryType == EntryTypeProject
 }
 
+// IsValidSlotType checks if a calendar slot type is valid
+func IsValidSlotType(slotType string) bool {
+	return slotType == SlotTypeFreeTime || slotType == SlotTypeBooking
+}
+
 // IsValidUserStatus checks if a user status is valid
 func IsValidUserStatus(status string) bool {
 	return status == UserStatusActive || status == UserStatusInactive
 }
+
+// Validate checks that a user can be persisted
+func (u *User) Validate() error {
+	if u.ReviewerLogin == "" {
+		return fmt.Errorf("reviewer login is required")
+	}
+	if !IsValidUserStatus(u.Status) {
+		return fmt.Errorf("%s: %q", ErrInvalidUserStatus, u.Status)
+	}
+	return nil
+}
+
+// Validate checks that a whitelist entry can be persisted
+func (e *WhitelistEntry) Validate() error {
+	if !IsValidEntryType(e.EntryType) {
+		return fmt.Errorf("%s: %q", ErrInvalidEntryType, e.EntryType)
+	}
+	if e.Name == "" {
+		return fmt.Errorf("whitelist entry name is required")
+	}
+	return nil
+}
+
+// NormalizeWhitelistName returns the canonical form of a whitelist entry name
+// Whitespace is trimmed and collapsed; FAMILY names get single spaces around dashes
+func NormalizeWhitelistName(entryType, name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if entryType != EntryTypeFamily {
+		return name
+	}
+
+	parts := strings.Split(name, "-")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.Join(parts, " - ")
+}
+
+// WhitelistNamesEqual reports whether two names refer to the same entry, ignoring case and spacing
+func WhitelistNamesEqual(entryType, a, b string) bool {
+	return strings.EqualFold(NormalizeWhitelistName(entryType, a), NormalizeWhitelistName(entryType, b))
+}
+
+// Normalize converts the entry to its canonical stored form
+func (e *WhitelistEntry) Normalize() {
+	e.ReviewerLogin = strings.TrimSpace(e.ReviewerLogin)
+	e.EntryType = strings.ToUpper(strings.TrimSpace(e.EntryType))
+	e.Name = NormalizeWhitelistName(e.EntryType, e.Name)
+}
+
+// SameEntry reports whether two entries refer to the same whitelisted item
+func (e *WhitelistEntry) SameEntry(other *WhitelistEntry) bool {
+	if other == nil || e.EntryType != other.EntryType {
+		return false
+	}
+	return WhitelistNamesEqual(e.EntryType, e.Name, other.Name)
+}
+
+// WhitelistIndex allows O(1) whitelist lookups keyed by normalized, lowercased names
+type WhitelistIndex struct {
+	Projects map[string]bool
+	Families map[string]bool
+}
+
+// IndexWhitelist builds project and family lookup sets from entries in one pass
+// Keys are whitelistIndexKey values, so lookups ignore case and spacing
+func IndexWhitelist(entries []*WhitelistEntry) (projects map[string]bool, families map[string]bool) {
+	projects = make(map[string]bool)
+	families = make(map[string]bool)
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		switch entry.EntryType {
+		case EntryTypeProject:
+			projects[whitelistIndexKey(EntryTypeProject, entry.Name)] = true
+		case EntryTypeFamily:
+			families[whitelistIndexKey(EntryTypeFamily, entry.Name)] = true
+		}
+	}
+	return projects, families
+}
+
+// NewWhitelistIndex builds a WhitelistIndex from entries
+func NewWhitelistIndex(entries []*WhitelistEntry) *WhitelistIndex {
+	projects, families := IndexWhitelist(entries)
+	return &WhitelistIndex{Projects: projects, Families: families}
+}
+
+// Matches reports whether the project or its family is whitelisted
+func (idx *WhitelistIndex) Matches(project, family string) bool {
+	if project != "" && idx.Projects[whitelistIndexKey(EntryTypeProject, project)] {
+		return true
+	}
+	return family != "" && idx.Families[whitelistIndexKey(EntryTypeFamily, family)]
+}
+
+// whitelistIndexKey returns the lookup key of a whitelist name
+func whitelistIndexKey(entryType, name string) string {
+	return strings.ToLower(NormalizeWhitelistName(entryType, name))
+}
+
+// Validate checks that a review request can be persisted
+func (r *ReviewRequest) Validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("%s: empty", ErrInvalidReviewID)
+	}
+	if r.ReviewerLogin == "" {
+		return fmt.Errorf("reviewer login is required")
+	}
+	if r.CalendarSlotID == "" {
+		return fmt.Errorf("calendar slot ID is required")
+	}
+	if !IsValidStatus(r.Status) {
+		return fmt.Errorf("%s: %q", ErrInvalidStatus, r.Status)
+	}
+	return nil
+}
+
+// IsActionable reports whether the request still needs handling at now: its status is intermediate
+// and its decision deadline is unset or still in the future
+func (r *ReviewRequest) IsActionable(now time.Time) bool {
+	if !IsIntermediateStatus(r.Status) {
+		return false
+	}
+	if r.DecisionDeadline == nil {
+		return true
+	}
+	return time.Unix(int64(*r.DecisionDeadline), 0).After(now)
+}
@@ -1,7 +1,14 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestStatusConstants(t *testing.T) {
@@ -171,6 +178,52 @@ func TestIsValidStatus(t *testing.T) {
 	}
 }
 
+func TestIsValidReviewID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		expected bool
+	}{
+		{"Valid UUID", "550e8400-e29b-41d4-a716-446655440000", true},
+		{"Generated UUID", NewReviewRequest("user", "slot", 0).ID, true},
+		{"Empty string", "", false},
+		{"Non-UUID string", "review-123", false},
+		{"UUID without dashes", "550e8400e29b41d4a716446655440000", false},
+		{"UUID in braces", "{550e8400-e29b-41d4-a716-446655440000}", false},
+		{"Invalid hex", "550e8400-e29b-41d4-a716-44665544zzzz", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsValidReviewID(tt.id); got != tt.expected {
+				t.Errorf("IsValidReviewID(%q) = %v, want %v", tt.id, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewReviewRequestID(t *testing.T) {
+	const calls = 1000
+	seen := make(map[string]struct{}, calls)
+
+	for i := 0; i < calls; i++ {
+		id := NewReviewRequestID()
+		if !IsValidReviewID(id) {
+			t.Fatalf("NewReviewRequestID() = %q, not a valid review ID", id)
+		}
+		if id != strings.ToLower(id) {
+			t.Fatalf("NewReviewRequestID() = %q, want lowercase", id)
+		}
+		if id[14] != '4' {
+			t.Fatalf("NewReviewRequestID() = %q, want UUID version 4", id)
+		}
+		if _, dup := seen[id]; dup {
+			t.Fatalf("NewReviewRequestID() returned duplicate %q", id)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
 func TestIsValidEntryType(t *testing.T) {
 	if !IsValidEntryType(EntryTypeFamily) {
 		t.Errorf("IsValidEntryType(FAMILY) should return true")
@@ -194,3 +247,671 @@ func TestIsValidUserStatus(t *testing.T) {
 		t.Errorf("IsValidUserStatus(INVALID) should return false")
 	}
 }
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		expected bool
+	}{
+		{StatusUnknownProjectReview, StatusKnownProjectReview, true},
+		{StatusKnownProjectReview, StatusWhitelisted, true},
+		{StatusKnownProjectReview, StatusNotWhitelisted, true},
+		{StatusWhitelisted, StatusNeedToApprove, true},
+		{StatusWhitelisted, StatusWaitingForApprove, true},
+		{StatusNeedToApprove, StatusWaitingForApprove, true},
+		{StatusWaitingForApprove, StatusApproved, true},
+		{StatusWaitingForApprove, StatusCancelled, true},
+		{StatusWaitingForApprove, StatusAutoCancelled, true},
+		{StatusNotWhitelisted, StatusWaitingForAutoCancel, true},
+		{StatusNotWhitelisted, StatusAutoCancelledNotWhitelisted, true},
+		{StatusWaitingForAutoCancel, StatusAutoCancelledNotWhitelisted, true},
+		{StatusUnknownProjectReview, StatusCancelled, true},
+
+		{StatusCancelled, StatusApproved, false},
+		{StatusApproved, StatusCancelled, false},
+		{StatusAutoCancelled, StatusWaitingForApprove, false},
+		{StatusAutoCancelledNotWhitelisted, StatusNotWhitelisted, false},
+		{StatusUnknownProjectReview, StatusApproved, false},
+		{StatusKnownProjectReview, StatusUnknownProjectReview, false},
+		{StatusNotWhitelisted, StatusApproved, false},
+		{StatusWaitingForApprove, StatusWhitelisted, false},
+		{StatusWaitingForApprove, StatusAutoCancelledNotWhitelisted, false},
+		{StatusWaitingForApprove, StatusWaitingForApprove, false},
+		{"INVALID", StatusApproved, false},
+		{StatusWhitelisted, "INVALID", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.expected {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFinalStatusesAreTerminal(t *testing.T) {
+	finalStates := []string{
+		StatusApproved,
+		StatusCancelled,
+		StatusAutoCancelled,
+		StatusAutoCancelledNotWhitelisted,
+	}
+
+	allStates := []string{
+		StatusUnknownProjectReview,
+		StatusKnownProjectReview,
+		StatusWhitelisted,
+		StatusNotWhitelisted,
+		StatusWaitingForAutoCancel,
+		StatusNeedToApprove,
+		StatusWaitingForApprove,
+		StatusApproved,
+		StatusCancelled,
+		StatusAutoCancelled,
+		StatusAutoCancelledNotWhitelisted,
+	}
+
+	for _, from := range finalStates {
+		for _, to := range allStates {
+			if CanTransition(from, to) {
+				t.Errorf("CanTransition(%s, %s) should return false for final status", from, to)
+			}
+		}
+	}
+}
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		name          string
+		from          string
+		to            string
+		errorContains string
+	}{
+		{"Legal transition", StatusWaitingForApprove, StatusApproved, ""},
+		{"Final status", StatusCancelled, StatusApproved, "is final"},
+		{"Illegal edge", StatusUnknownProjectReview, StatusApproved, "cannot change to"},
+		{"Unknown current status", "INVALID", StatusApproved, "unknown current status"},
+		{"Unknown target status", StatusWhitelisted, "INVALID", "unknown target status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTransition(tt.from, tt.to)
+			if tt.errorContains == "" {
+				if err != nil {
+					t.Errorf("ValidateTransition(%s, %s) returned unexpected error: %v", tt.from, tt.to, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ValidateTransition(%s, %s) should return an error", tt.from, tt.to)
+			}
+			if !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("ValidateTransition(%s, %s) error = %q, want it to contain %q", tt.from, tt.to, err.Error(), tt.errorContains)
+			}
+		})
+	}
+}
+
+func TestUserValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		user        User
+		expectError bool
+	}{
+		{"Valid active user", User{ReviewerLogin: "testuser", Status: UserStatusActive}, false},
+		{"Valid inactive user", User{ReviewerLogin: "testuser", Status: UserStatusInactive}, false},
+		{"Empty login", User{ReviewerLogin: "", Status: UserStatusActive}, true},
+		{"Invalid status", User{ReviewerLogin: "testuser", Status: "BANNED"}, true},
+		{"Empty status", User{ReviewerLogin: "testuser"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.user.Validate()
+			if tt.expectError && err == nil {
+				t.Errorf("Validate() should return an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWhitelistEntryValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		entry       WhitelistEntry
+		expectError bool
+	}{
+		{"Valid family entry", WhitelistEntry{ReviewerLogin: "testuser", EntryType: EntryTypeFamily, Name: "C"}, false},
+		{"Valid project entry", WhitelistEntry{ReviewerLogin: "testuser", EntryType: EntryTypeProject, Name: "C2_SimpleBashUtils"}, false},
+		{"Invalid entry type", WhitelistEntry{ReviewerLogin: "testuser", EntryType: "GROUP", Name: "C"}, true},
+		{"Empty name", WhitelistEntry{ReviewerLogin: "testuser", EntryType: EntryTypeProject, Name: ""}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.Validate()
+			if tt.expectError && err == nil {
+				t.Errorf("Validate() should return an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestReviewRequestValidate(t *testing.T) {
+	valid := ReviewRequest{
+		ID:             "550e8400-e29b-41d4-a716-446655440000",
+		ReviewerLogin:  "testuser",
+		CalendarSlotID: "slot-123",
+		Status:         StatusUnknownProjectReview,
+	}
+
+	tests := []struct {
+		name        string
+		modify      func(r *ReviewRequest)
+		expectError bool
+	}{
+		{"Valid request", func(r *ReviewRequest) {}, false},
+		{"Empty ID", func(r *ReviewRequest) { r.ID = "" }, true},
+		{"Empty reviewer login", func(r *ReviewRequest) { r.ReviewerLogin = "" }, true},
+		{"Empty calendar slot ID", func(r *ReviewRequest) { r.CalendarSlotID = "" }, true},
+		{"Invalid status", func(r *ReviewRequest) { r.Status = "PENDING" }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			err := req.Validate()
+			if tt.expectError && err == nil {
+				t.Errorf("Validate() should return an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Validate() returned unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeWhitelistName(t *testing.T) {
+	tests := []struct {
+		name      string
+		entryType string
+		input     string
+		expected  string
+	}{
+		{"Family without spaces", EntryTypeFamily, "Go-I", "Go - I"},
+		{"Family with extra spaces", EntryTypeFamily, "  Go   -   I ", "Go - I"},
+		{"Family without dash", EntryTypeFamily, " C ", "C"},
+		{"Project keeps dashes", EntryTypeProject, " C2_s21-string+ ", "C2_s21-string+"},
+		{"Project collapses spaces", EntryTypeProject, "Go  Boot   camp", "Go Boot camp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeWhitelistName(tt.entryType, tt.input); got != tt.expected {
+				t.Errorf("NormalizeWhitelistName(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWhitelistIndex(t *testing.T) {
+	entries := []*WhitelistEntry{
+		{ReviewerLogin: "testuser", EntryType: EntryTypeProject, Name: "DO1_Linux"},
+		{ReviewerLogin: "testuser", EntryType: EntryTypeFamily, Name: "Go - I"},
+		nil,
+	}
+
+	projects, families := IndexWhitelist(entries)
+	if len(projects) != 1 || len(families) != 1 {
+		t.Fatalf("IndexWhitelist() = %v, %v; want one project and one family", projects, families)
+	}
+
+	idx := NewWhitelistIndex(entries)
+	tests := []struct {
+		name     string
+		project  string
+		family   string
+		expected bool
+	}{
+		{"Project hit", "do1_linux", "DevOps", true},
+		{"Family hit", "GO1_Boot", "go-i", true},
+		{"Miss", "C2_SimpleBashUtils", "C", false},
+		{"Empty names", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.Matches(tt.project, tt.family); got != tt.expected {
+				t.Errorf("Matches(%q, %q) = %v, want %v", tt.project, tt.family, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWhitelistEntryNormalize(t *testing.T) {
+	a := WhitelistEntry{ReviewerLogin: " testuser ", EntryType: "family", Name: "Go - I"}
+	b := WhitelistEntry{ReviewerLogin: "testuser", EntryType: EntryTypeFamily, Name: "go-i"}
+
+	a.Normalize()
+	b.Normalize()
+
+	if a.ReviewerLogin != "testuser" || a.EntryType != EntryTypeFamily || a.Name != "Go - I" {
+		t.Errorf("Normalize() produced %+v", a)
+	}
+	if b.Name != "go - i" {
+		t.Errorf("Normalize() should keep case, got %q", b.Name)
+	}
+	if !a.SameEntry(&b) {
+		t.Errorf("SameEntry() should match differently-spaced inputs")
+	}
+
+	project := WhitelistEntry{EntryType: EntryTypeProject, Name: "go - i"}
+	if a.SameEntry(&project) {
+		t.Errorf("SameEntry() should not match entries of different types")
+	}
+	if a.SameEntry(nil) {
+		t.Errorf("SameEntry() should not match nil")
+	}
+}
+
+func TestReviewRequestJSONRoundTrip(t *testing.T) {
+	project := "C2_SimpleBashUtils"
+	deadline := uint32(1705312800)
+	decidedAt := uint32(1705313400)
+
+	tests := []struct {
+		name     string
+		req      ReviewRequest
+		contains []string
+	}{
+		{
+			name: "With optional timestamps",
+			req: ReviewRequest{
+				ID:               "550e8400-e29b-41d4-a716-446655440000",
+				ReviewerLogin:    "testuser",
+				ProjectName:      &project,
+				ReviewStartTime:  1705316400,
+				CalendarSlotID:   "slot-123",
+				Status:           StatusApproved,
+				DecisionDeadline: &deadline,
+				CreatedAt:        1705309200,
+				DecidedAt:        &decidedAt,
+			},
+			contains: []string{
+				`"review_start_time":"2024-01-15T11:00:00Z"`,
+				`"decision_deadline":"2024-01-15T10:00:00Z"`,
+				`"created_at":"2024-01-15T09:00:00Z"`,
+				`"decided_at":"2024-01-15T10:10:00Z"`,
+				`"project_name":"C2_SimpleBashUtils"`,
+			},
+		},
+		{
+			name: "Without optional timestamps",
+			req: ReviewRequest{
+				ID:              "550e8400-e29b-41d4-a716-446655440001",
+				ReviewerLogin:   "testuser",
+				ReviewStartTime: 1705316400,
+				CalendarSlotID:  "slot-456",
+				Status:          StatusUnknownProjectReview,
+				CreatedAt:       1705309200,
+			},
+			contains: []string{
+				`"decision_deadline":null`,
+				`"non_whitelist_cancel_at":null`,
+				`"decided_at":null`,
+				`"project_name":null`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.req)
+			if err != nil {
+				t.Fatalf("Marshal() returned error: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("Marshal() = %s, want it to contain %s", data, want)
+				}
+			}
+
+			var decoded ReviewRequest
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unmarshal() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.req) {
+				t.Errorf("round trip = %+v, want %+v", decoded, tt.req)
+			}
+		})
+	}
+}
+
+func TestReviewRequestUnmarshalJSONRejectsOutOfRange(t *testing.T) {
+	var req ReviewRequest
+	err := json.Unmarshal([]byte(`{"id":"x","review_start_time":"1969-12-31T23:59:59Z","created_at":"2024-01-15T09:00:00Z"}`), &req)
+	if err == nil {
+		t.Errorf("Unmarshal() should reject timestamps before the unix epoch")
+	}
+}
+
+func TestCalendarSlotDurationAndValidity(t *testing.T) {
+	start := int64(1705309200)
+
+	tests := []struct {
+		name             string
+		slot             CalendarSlot
+		expectedDuration int
+		expectedValid    bool
+	}{
+		{"Normal slot", CalendarSlot{ID: "slot-1", Start: start, End: start + 90*60, Type: SlotTypeFreeTime}, 90, true},
+		{"Booking slot with partial minute", CalendarSlot{ID: "slot-2", Start: start, End: start + 30*60 + 59, Type: SlotTypeBooking}, 30, true},
+		{"Zero-length slot", CalendarSlot{ID: "slot-3", Start: start, End: start, Type: SlotTypeFreeTime}, 0, false},
+		{"Inverted slot", CalendarSlot{ID: "slot-4", Start: start, End: start - 60, Type: SlotTypeFreeTime}, 0, false},
+		{"Empty ID", CalendarSlot{Start: start, End: start + 60, Type: SlotTypeFreeTime}, 1, false},
+		{"Unknown type", CalendarSlot{ID: "slot-5", Start: start, End: start + 60, Type: "MEETING"}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.slot.DurationMinutes(); got != tt.expectedDuration {
+				t.Errorf("DurationMinutes() = %d, want %d", got, tt.expectedDuration)
+			}
+			if got := tt.slot.IsValid(); got != tt.expectedValid {
+				t.Errorf("IsValid() = %v, want %v", got, tt.expectedValid)
+			}
+		})
+	}
+}
+
+func TestUserHasStaleAuth(t *testing.T) {
+	cutoff := int64(1705309200)
+	ts := func(v int64) *uint32 {
+		u := uint32(v)
+		return &u
+	}
+
+	tests := []struct {
+		name     string
+		user     User
+		expected bool
+	}{
+		{"Keeps failing since before cutoff", User{Status: UserStatusActive, CreatedAt: 1, LastAuthSuccessAt: ts(cutoff - 3600), LastAuthFailureAt: ts(cutoff + 60)}, true},
+		{"Recovered after failure", User{Status: UserStatusActive, CreatedAt: 1, LastAuthSuccessAt: ts(cutoff + 120), LastAuthFailureAt: ts(cutoff + 60)}, false},
+		{"Failing but succeeded after cutoff", User{Status: UserStatusActive, CreatedAt: 1, LastAuthSuccessAt: ts(cutoff + 10), LastAuthFailureAt: ts(cutoff + 60)}, false},
+		{"Never succeeded, created before cutoff", User{Status: UserStatusActive, CreatedAt: uint32(cutoff - 60), LastAuthFailureAt: ts(cutoff + 60)}, true},
+		{"Never succeeded, created after cutoff", User{Status: UserStatusActive, CreatedAt: uint32(cutoff + 10), LastAuthFailureAt: ts(cutoff + 60)}, false},
+		{"Never failed", User{Status: UserStatusActive, CreatedAt: 1, LastAuthSuccessAt: ts(cutoff - 3600)}, false},
+		{"Already inactive", User{Status: UserStatusInactive, CreatedAt: 1, LastAuthFailureAt: ts(cutoff + 60)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.HasStaleAuth(cutoff); got != tt.expected {
+				t.Errorf("HasStaleAuth() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUserTokensIsExpired(t *testing.T) {
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name     string
+		tokens   UserTokens
+		buffer   time.Duration
+		expected bool
+	}{
+		{"Expired", UserTokens{AccessToken: "token", ExpiryTime: now - 60}, 0, true},
+		{"Within buffer", UserTokens{AccessToken: "token", ExpiryTime: now + 30}, time.Minute, true},
+		{"Valid", UserTokens{AccessToken: "token", ExpiryTime: now + 3600}, time.Minute, false},
+		{"Missing access token", UserTokens{ExpiryTime: now + 3600}, 0, true},
+		{"Unknown expiry", UserTokens{AccessToken: "token"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tokens.IsExpired(tt.buffer); got != tt.expected {
+				t.Errorf("IsExpired() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewReviewRequest(t *testing.T) {
+	before := uint32(time.Now().Unix())
+	req := NewReviewRequest("testuser", "slot-123", 1705316400)
+	after := uint32(time.Now().Unix())
+
+	if req.ReviewerLogin != "testuser" || req.CalendarSlotID != "slot-123" || req.ReviewStartTime != 1705316400 {
+		t.Errorf("NewReviewRequest() did not copy arguments: %+v", req)
+	}
+	if req.Status != StatusUnknownProjectReview {
+		t.Errorf("Status = %q, want %q", req.Status, StatusUnknownProjectReview)
+	}
+	if req.CreatedAt < before || req.CreatedAt > after {
+		t.Errorf("CreatedAt = %d, want between %d and %d", req.CreatedAt, before, after)
+	}
+	if req.ProjectName != nil || req.FamilyLabel != nil || req.DecisionDeadline != nil || req.DecidedAt != nil {
+		t.Errorf("optional fields should be nil: %+v", req)
+	}
+	if err := req.Validate(); err != nil {
+		t.Errorf("Validate() returned unexpected error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewReviewRequest("testuser", "slot-123", 1705316400).ID
+		if len(id) != 36 {
+			t.Errorf("ID %q is not a UUID", id)
+		}
+		if seen[id] {
+			t.Errorf("duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestReviewRequestIsActionable(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	deadline := func(d time.Duration) *uint32 {
+		v := uint32(now.Add(d).Unix())
+		return &v
+	}
+
+	tests := []struct {
+		name     string
+		status   string
+		deadline *uint32
+		want     bool
+	}{
+		{"intermediate without deadline", StatusWaitingForApprove, nil, true},
+		{"intermediate with future deadline", StatusWaitingForApprove, deadline(time.Minute), true},
+		{"intermediate with expired deadline", StatusWaitingForApprove, deadline(-time.Minute), false},
+		{"deadline exactly now", StatusWaitingForApprove, deadline(0), false},
+		{"final status", StatusApproved, deadline(time.Hour), false},
+		{"final status without deadline", StatusCancelled, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ReviewRequest{Status: tt.status, DecisionDeadline: tt.deadline}
+			if got := r.IsActionable(now); got != tt.want {
+				t.Errorf("IsActionable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDefaultUserSettings(t *testing.T) {
+	writeConfig := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "defaults.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		return path
+	}
+	t.Cleanup(func() { loadedUserSettings.Store(nil) })
+
+	t.Run("valid override file", func(t *testing.T) {
+		t.Cleanup(func() { loadedUserSettings.Store(nil) })
+		path := writeConfig(t, `{"response_deadline_shift_minutes": 45, "notify_whitelist_timeout": false, "cleanup_durations_minutes": 30}`)
+
+		if err := LoadDefaultUserSettings(path); err != nil {
+			t.Fatalf("LoadDefaultUserSettings() error = %v", err)
+		}
+
+		got := DefaultUserSettings("testuser")
+		want := &UserSettings{
+			ReviewerLogin:                  "testuser",
+			ResponseDeadlineShiftMinutes:   45,
+			NonWhitelistCancelDelayMinutes: 5,
+			NotifyWhitelistTimeout:         false,
+			NotifyNonWhitelistCancel:       true,
+			SlotShiftThresholdMinutes:      25,
+			SlotShiftDurationMinutes:       15,
+			CleanupDurationsMinutes:        30,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("DefaultUserSettings() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("out of range value is rejected", func(t *testing.T) {
+		t.Cleanup(func() { loadedUserSettings.Store(nil) })
+		path := writeConfig(t, `{"slot_shift_duration_minutes": 500}`)
+
+		err := LoadDefaultUserSettings(path)
+		if err == nil || !strings.Contains(err.Error(), "slot_shift_duration_minutes") {
+			t.Fatalf("LoadDefaultUserSettings() error = %v, want range error", err)
+		}
+		if got := DefaultUserSettings("").SlotShiftDurationMinutes; got != 15 {
+			t.Errorf("SlotShiftDurationMinutes = %d after rejected load, want 15", got)
+		}
+	})
+
+	t.Run("unknown key is rejected", func(t *testing.T) {
+		path := writeConfig(t, `{"slot_shift_minutes": 10}`)
+
+		if err := LoadDefaultUserSettings(path); err == nil {
+			t.Error("LoadDefaultUserSettings() with unknown key should fail")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if err := LoadDefaultUserSettings(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+			t.Error("LoadDefaultUserSettings() with missing file should fail")
+		}
+	})
+}
+
+func TestTokenResponseToUserTokens(t *testing.T) {
+	const now = int64(1700000000)
+
+	tests := []struct {
+		name string
+		tr   TokenResponse
+		want UserTokens
+	}{
+		{
+			name: "explicit timestamps are kept",
+			tr: TokenResponse{
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				ExpiresIn:    300,
+				IssueTime:    now - 60,
+				ExpiryTime:   now + 240,
+			},
+			want: UserTokens{
+				ReviewerLogin: "testuser",
+				AccessToken:   "access",
+				RefreshToken:  "refresh",
+				CreatedAt:     uint32(now),
+				UpdatedAt:     uint32(now),
+				IssueTime:     now - 60,
+				ExpiryTime:    now + 240,
+			},
+		},
+		{
+			name: "expiry derived from ExpiresIn",
+			tr: TokenResponse{
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				ExpiresIn:    300,
+			},
+			want: UserTokens{
+				ReviewerLogin: "testuser",
+				AccessToken:   "access",
+				RefreshToken:  "refresh",
+				CreatedAt:     uint32(now),
+				UpdatedAt:     uint32(now),
+				IssueTime:     now,
+				ExpiryTime:    now + 300,
+			},
+		},
+		{
+			name: "expiry derived from issue time",
+			tr:   TokenResponse{AccessToken: "access", ExpiresIn: 300, IssueTime: now - 100},
+			want: UserTokens{
+				ReviewerLogin: "testuser",
+				AccessToken:   "access",
+				CreatedAt:     uint32(now),
+				UpdatedAt:     uint32(now),
+				IssueTime:     now - 100,
+				ExpiryTime:    now + 200,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tr.ToUserTokens("testuser", now); got != tt.want {
+				t.Errorf("ToUserTokens() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokensRedactedWhenFormatted(t *testing.T) {
+	const access, refresh = "secret-access-token", "secret-refresh-token"
+	tokens := UserTokens{ReviewerLogin: "testuser", AccessToken: access, RefreshToken: refresh, ExpiryTime: 1700000300}
+	response := TokenResponse{AccessToken: access, RefreshToken: refresh, ExpiresIn: 300, TokenType: "Bearer"}
+
+	formats := []string{"%v", "%+v", "%#v", "%s"}
+	for _, format := range formats {
+		for _, value := range []any{tokens, &tokens, response, &response} {
+			out := fmt.Sprintf(format, value)
+			if strings.Contains(out, access) || strings.Contains(out, refresh) {
+				t.Errorf("fmt.Sprintf(%q, %T) leaked a token: %s", format, value, out)
+			}
+			if !strings.Contains(out, redactedSecret) {
+				t.Errorf("fmt.Sprintf(%q, %T) = %s, want redacted marker", format, value, out)
+			}
+		}
+	}
+
+	if !strings.Contains(fmt.Sprintf("%+v", tokens), "ReviewerLogin:testuser") {
+		t.Errorf("non-secret fields should still be printed: %+v", tokens)
+	}
+	if tokens.AccessToken != access || tokens.RefreshToken != refresh {
+		t.Error("token fields should keep the real values")
+	}
+	if got := SecretString(access).Value(); got != access {
+		t.Errorf("SecretString.Value() = %q, want %q", got, access)
+	}
+	if got := SecretString("").String(); got != "" {
+		t.Errorf("empty SecretString.String() = %q, want empty", got)
+	}
+}
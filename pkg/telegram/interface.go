@@ -1,25 +1,72 @@
 package telegram
 
+import "context"
+
 // BotSender defines the interface for sending Telegram messages
 type BotSender interface {
 	// SendPlainMessage sends a plain text message
 	SendPlainMessage(chatID int64, text string) error
 
+	// SendPlainMessageCtx sends a plain text message, aborting if ctx is done
+	SendPlainMessageCtx(ctx context.Context, chatID int64, text string) error
+
+	// SendMessageBatch sends the same plain text message to every chat, never aborting early
+	SendMessageBatch(chatIDs []int64, text string) ([]int64, map[int64]error)
+
+	// SendMessageBatchCtx sends a plain text message to every chat, recording ctx errors for unsent chats
+	SendMessageBatchCtx(ctx context.Context, chatIDs []int64, text string) ([]int64, map[int64]error)
+
+	// SendPlainMessageV2 sends a text message using MarkdownV2 parse mode
+	SendPlainMessageV2(chatID int64, text string) error
+
+	// SendPlainMessageV2Ctx sends a MarkdownV2 text message, aborting if ctx is done
+	SendPlainMessageV2Ctx(ctx context.Context, chatID int64, text string) error
+
 	// SendInlineKeyboardMessage sends a message with inline keyboard buttons
 	SendInlineKeyboardMessage(chatID int64, text string, buttons []InlineKeyboardButton) (int, error)
 
+	// SendInlineKeyboardMessageCtx sends a message with inline keyboard buttons, aborting if ctx is done
+	SendInlineKeyboardMessageCtx(ctx context.Context, chatID int64, text string, buttons []InlineKeyboardButton) (int, error)
+
+	// SendInlineKeyboardMessageWithParseMode sends a keyboard message using the given parse mode
+	SendInlineKeyboardMessageWithParseMode(chatID int64, text, parseMode string, buttons []InlineKeyboardButton) (int, error)
+
+	// SendInlineKeyboardMessageWithParseModeCtx sends a keyboard message using the given parse mode, aborting if ctx is done
+	SendInlineKeyboardMessageWithParseModeCtx(ctx context.Context, chatID int64, text, parseMode string, buttons []InlineKeyboardButton) (int, error)
+
 	// SendTwoButtonKeyboard sends a message with two buttons (Approve/Decline pattern)
 	SendTwoButtonKeyboard(chatID int64, text string, approveData, declineData string) (int, error)
 
+	// SendTwoButtonKeyboardCtx sends an Approve/Decline keyboard message, aborting if ctx is done
+	SendTwoButtonKeyboardCtx(ctx context.Context, chatID int64, text string, approveData, declineData string) (int, error)
+
 	// EditMessage edits an existing message
 	EditMessage(chatID int64, messageID int, text string) error
 
+	// EditMessageCtx edits an existing message, aborting if ctx is done
+	EditMessageCtx(ctx context.Context, chatID int64, messageID int, text string) error
+
 	// EditMessageWithKeyboard edits a message and adds a keyboard
 	EditMessageWithKeyboard(chatID int64, messageID int, text string, buttons []InlineKeyboardButton) error
 
+	// EditMessageWithKeyboardCtx edits a message and adds a keyboard, aborting if ctx is done
+	EditMessageWithKeyboardCtx(ctx context.Context, chatID int64, messageID int, text string, buttons []InlineKeyboardButton) error
+
 	// AnswerCallbackQuery acknowledges a button click
 	AnswerCallbackQuery(callbackQueryID, text string) error
 
+	// AnswerCallbackQueryCtx acknowledges a button click, aborting if ctx is done
+	AnswerCallbackQueryCtx(ctx context.Context, callbackQueryID, text string) error
+
+	// AnswerCallbackQueryWithURL acknowledges a button click by opening url
+	AnswerCallbackQueryWithURL(callbackQueryID, url string) error
+
+	// AnswerCallbackQueryWithURLCtx acknowledges a button click by opening url, aborting if ctx is done
+	AnswerCallbackQueryWithURLCtx(ctx context.Context, callbackQueryID, url string) error
+
 	// DeleteMessage deletes a message
 	DeleteMessage(chatID int64, messageID int) error
+
+	// DeleteMessageCtx deletes a message, aborting if ctx is done
+	DeleteMessageCtx(ctx context.Context, chatID int64, messageID int) error
 }
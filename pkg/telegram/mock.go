@@ -1,6 +1,8 @@
 package telegram
 
 import (
+	"context"
+
 	"github.com/stretchr/testify/mock"
 )
 
@@ -20,38 +22,141 @@ func (m *MockBotSender) SendPlainMessage(chatID int64, text string) error {
 	return args.Error(0)
 }
 
+// SendPlainMessageCtx sends a plain text message, aborting if ctx is done
+func (m *MockBotSender) SendPlainMessageCtx(ctx context.Context, chatID int64, text string) error {
+	args := m.Called(ctx, chatID, text)
+	return args.Error(0)
+}
+
+// SendMessageBatch sends the same plain text message to every chat, never aborting early
+func (m *MockBotSender) SendMessageBatch(chatIDs []int64, text string) ([]int64, map[int64]error) {
+	args := m.Called(chatIDs, text)
+	return mockBatchResult(args)
+}
+
+// SendMessageBatchCtx sends a plain text message to every chat, recording ctx errors for unsent chats
+func (m *MockBotSender) SendMessageBatchCtx(ctx context.Context, chatIDs []int64, text string) ([]int64, map[int64]error) {
+	args := m.Called(ctx, chatIDs, text)
+	return mockBatchResult(args)
+}
+
+// mockBatchResult converts mock arguments into SendMessageBatch return values
+func mockBatchResult(args mock.Arguments) ([]int64, map[int64]error) {
+	var sent []int64
+	if v := args.Get(0); v != nil {
+		sent = v.([]int64)
+	}
+	var failures map[int64]error
+	if v := args.Get(1); v != nil {
+		failures = v.(map[int64]error)
+	}
+	return sent, failures
+}
+
+// SendPlainMessageV2 sends a text message using MarkdownV2 parse mode
+func (m *MockBotSender) SendPlainMessageV2(chatID int64, text string) error {
+	args := m.Called(chatID, text)
+	return args.Error(0)
+}
+
+// SendPlainMessageV2Ctx sends a MarkdownV2 text message, aborting if ctx is done
+func (m *MockBotSender) SendPlainMessageV2Ctx(ctx context.Context, chatID int64, text string) error {
+	args := m.Called(ctx, chatID, text)
+	return args.Error(0)
+}
+
 // SendInlineKeyboardMessage sends a message with inline keyboard buttons
 func (m *MockBotSender) SendInlineKeyboardMessage(chatID int64, text string, buttons []InlineKeyboardButton) (int, error) {
 	args := m.Called(chatID, text, buttons)
 	return args.Int(0), args.Error(1)
 }
 
+// SendInlineKeyboardMessageCtx sends a message with inline keyboard buttons, aborting if ctx is done
+func (m *MockBotSender) SendInlineKeyboardMessageCtx(ctx context.Context, chatID int64, text string, buttons []InlineKeyboardButton) (int, error) {
+	args := m.Called(ctx, chatID, text, buttons)
+	return args.Int(0), args.Error(1)
+}
+
+// SendInlineKeyboardMessageWithParseMode sends a keyboard message using the given parse mode
+func (m *MockBotSender) SendInlineKeyboardMessageWithParseMode(chatID int64, text, parseMode string, buttons []InlineKeyboardButton) (int, error) {
+	args := m.Called(chatID, text, parseMode, buttons)
+	return args.Int(0), args.Error(1)
+}
+
+// SendInlineKeyboardMessageWithParseModeCtx sends a keyboard message using the given parse mode, aborting if ctx is done
+func (m *MockBotSender) SendInlineKeyboardMessageWithParseModeCtx(ctx context.Context, chatID int64, text, parseMode string, buttons []InlineKeyboardButton) (int, error) {
+	args := m.Called(ctx, chatID, text, parseMode, buttons)
+	return args.Int(0), args.Error(1)
+}
+
 // SendTwoButtonKeyboard sends a message with two buttons (Approve/Decline pattern)
 func (m *MockBotSender) SendTwoButtonKeyboard(chatID int64, text string, approveData, declineData string) (int, error) {
 	args := m.Called(chatID, text, approveData, declineData)
 	return args.Int(0), args.Error(1)
 }
 
+// SendTwoButtonKeyboardCtx sends an Approve/Decline keyboard message, aborting if ctx is done
+func (m *MockBotSender) SendTwoButtonKeyboardCtx(ctx context.Context, chatID int64, text string, approveData, declineData string) (int, error) {
+	args := m.Called(ctx, chatID, text, approveData, declineData)
+	return args.Int(0), args.Error(1)
+}
+
 // EditMessage edits an existing message
 func (m *MockBotSender) EditMessage(chatID int64, messageID int, text string) error {
 	args := m.Called(chatID, messageID, text)
 	return args.Error(0)
 }
 
+// EditMessageCtx edits an existing message, aborting if ctx is done
+func (m *MockBotSender) EditMessageCtx(ctx context.Context, chatID int64, messageID int, text string) error {
+	args := m.Called(ctx, chatID, messageID, text)
+	return args.Error(0)
+}
+
 // EditMessageWithKeyboard edits a message and adds a keyboard
 func (m *MockBotSender) EditMessageWithKeyboard(chatID int64, messageID int, text string, buttons []InlineKeyboardButton) error {
 	args := m.Called(chatID, messageID, text, buttons)
 	return args.Error(0)
 }
 
+// EditMessageWithKeyboardCtx edits a message and adds a keyboard, aborting if ctx is done
+func (m *MockBotSender) EditMessageWithKeyboardCtx(ctx context.Context, chatID int64, messageID int, text string, buttons []InlineKeyboardButton) error {
+	args := m.Called(ctx, chatID, messageID, text, buttons)
+	return args.Error(0)
+}
+
 // AnswerCallbackQuery acknowledges a button click
 func (m *MockBotSender) AnswerCallbackQuery(callbackQueryID, text string) error {
 	args := m.Called(callbackQueryID, text)
 	return args.Error(0)
 }
 
+// AnswerCallbackQueryCtx acknowledges a button click, aborting if ctx is done
+func (m *MockBotSender) AnswerCallbackQueryCtx(ctx context.Context, callbackQueryID, text string) error {
+	args := m.Called(ctx, callbackQueryID, text)
+	return args.Error(0)
+}
+
+// AnswerCallbackQueryWithURL acknowledges a button click by opening url
+func (m *MockBotSender) AnswerCallbackQueryWithURL(callbackQueryID, url string) error {
+	args := m.Called(callbackQueryID, url)
+	return args.Error(0)
+}
+
+// AnswerCallbackQueryWithURLCtx acknowledges a button click by opening url, aborting if ctx is done
+func (m *MockBotSender) AnswerCallbackQueryWithURLCtx(ctx context.Context, callbackQueryID, url string) error {
+	args := m.Called(ctx, callbackQueryID, url)
+	return args.Error(0)
+}
+
 // DeleteMessage deletes a message
 func (m *MockBotSender) DeleteMessage(chatID int64, messageID int) error {
 	args := m.Called(chatID, messageID)
 	return args.Error(0)
 }
+
+// DeleteMessageCtx deletes a message, aborting if ctx is done
+func (m *MockBotSender) DeleteMessageCtx(ctx context.Context, chatID int64, messageID int) error {
+	args := m.Called(ctx, chatID, messageID)
+	return args.Error(0)
+}
@@ -1,15 +1,38 @@
 package telegram
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	tba "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
+)
+
+// Default retry policy for rate-limited (429) Telegram responses
+const (
+	DefaultMaxRetries   = 3
+	DefaultMaxRetryWait = 30 * time.Second
 )
 
+// ErrMessageNotFound is returned when the message to edit or delete no longer exists
+var ErrMessageNotFound = errors.New("telegram message not found")
+
+// botAPI is the subset of the Telegram Bot API used by BotClient
+type botAPI interface {
+	Send(c tba.Chattable) (tba.Message, error)
+}
+
 // BotClient wraps Telegram Bot API client
 type BotClient struct {
-	bot *tba.BotAPI
+	bot          botAPI
+	maxRetries   int
+	maxRetryWait time.Duration
 }
 
 // MessageConfig holds configuration for sending messages
@@ -35,9 +58,11 @@ type CallbackConfig struct {
 }
 
 // InlineKeyboardButton represents a button in an inline keyboard
+// Exactly one of Data (callback button) or URL (link button) must be set
 type InlineKeyboardButton struct {
 	Text string
 	Data string
+	URL  string
 }
 
 // NewBotClient creates a new Telegram bot client
@@ -46,7 +71,16 @@ func NewBotClient(token string) (*BotClient, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Telegram bot: %w", err)
 	}
-	return &BotClient{bot: bot}, nil
+	return newBotClientWithAPI(bot), nil
+}
+
+// newBotClientWithAPI creates a bot client around any botAPI implementation
+func newBotClientWithAPI(bot botAPI) *BotClient {
+	return &BotClient{
+		bot:          bot,
+		maxRetries:   DefaultMaxRetries,
+		maxRetryWait: DefaultMaxRetryWait,
+	}
 }
 
 // NewBotClientFromEnv creates a new Telegram bot client using TELEGRAM_BOT_TOKEN env var
@@ -58,36 +92,146 @@ func NewBotClientFromEnv() (*BotClient, error) {
 	return NewBotClient(token)
 }
 
+// SetRetryPolicy configures how many times a rate-limited send is retried
+// and the maximum time to wait between attempts
+func (bc *BotClient) SetRetryPolicy(maxRetries int, maxRetryWait time.Duration) {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	bc.maxRetries = maxRetries
+	bc.maxRetryWait = maxRetryWait
+}
+
+// send sends a Chattable, retrying when Telegram responds with 429 Too Many Requests
+// The underlying library has no context support, so ctx is checked before every attempt
+func (bc *BotClient) send(ctx context.Context, c tba.Chattable) (tba.Message, error) {
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return tba.Message{}, err
+		}
+
+		msg, err := bc.bot.Send(c)
+		if err == nil {
+			return msg, nil
+		}
+
+		wait, limited := retryAfter(err)
+		if !limited || attempt >= bc.maxRetries {
+			return msg, err
+		}
+		if wait > bc.maxRetryWait {
+			wait = bc.maxRetryWait
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return tba.Message{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter reports whether err is a Telegram rate-limit error and how long to wait
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *tba.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		return 0, false
+	}
+	return time.Duration(apiErr.RetryAfter) * time.Second, true
+}
+
 // SendPlainMessage sends a plain text message
 func (bc *BotClient) SendPlainMessage(chatID int64, text string) error {
+	return bc.SendPlainMessageCtx(context.Background(), chatID, text)
+}
+
+// SendPlainMessageCtx sends a plain text message, aborting if ctx is done
+func (bc *BotClient) SendPlainMessageCtx(ctx context.Context, chatID int64, text string) error {
 	msg := tba.NewMessage(chatID, text)
-	_, err := bc.bot.Send(msg)
+	_, err := bc.send(ctx, msg)
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
 	return nil
 }
 
+// SendMessageBatch sends the same plain text message to every chat, never aborting early
+// It returns the chats that received the message and the error for each chat that did not
+func (bc *BotClient) SendMessageBatch(chatIDs []int64, text string) ([]int64, map[int64]error) {
+	return bc.SendMessageBatchCtx(context.Background(), chatIDs, text)
+}
+
+// SendMessageBatchCtx sends a plain text message to every chat, recording ctx errors for unsent chats
+func (bc *BotClient) SendMessageBatchCtx(ctx context.Context, chatIDs []int64, text string) ([]int64, map[int64]error) {
+	var sent []int64
+	failures := make(map[int64]error)
+
+	for _, chatID := range chatIDs {
+		if err := bc.SendPlainMessageCtx(ctx, chatID, text); err != nil {
+			failures[chatID] = err
+			continue
+		}
+		sent = append(sent, chatID)
+	}
+
+	return sent, failures
+}
+
+// SendPlainMessageV2 sends a text message using MarkdownV2 parse mode
+// Interpolated values must be escaped with EscapeMarkdownV2
+func (bc *BotClient) SendPlainMessageV2(chatID int64, text string) error {
+	return bc.SendPlainMessageV2Ctx(context.Background(), chatID, text)
+}
+
+// SendPlainMessageV2Ctx sends a MarkdownV2 text message, aborting if ctx is done
+func (bc *BotClient) SendPlainMessageV2Ctx(ctx context.Context, chatID int64, text string) error {
+	msg := tba.NewMessage(chatID, text)
+	msg.ParseMode = tba.ModeMarkdownV2
+	_, err := bc.send(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("failed to send MarkdownV2 message: %w", err)
+	}
+	return nil
+}
+
 // SendInlineKeyboardMessage sends a message with inline keyboard buttons
 func (bc *BotClient) SendInlineKeyboardMessage(chatID int64, text string, buttons []InlineKeyboardButton) (int, error) {
+	return bc.SendInlineKeyboardMessageCtx(context.Background(), chatID, text, buttons)
+}
+
+// SendInlineKeyboardMessageCtx sends a message with inline keyboard buttons, aborting if ctx is done
+func (bc *BotClient) SendInlineKeyboardMessageCtx(ctx context.Context, chatID int64, text string, buttons []InlineKeyboardButton) (int, error) {
+	return bc.SendInlineKeyboardMessageWithParseModeCtx(ctx, chatID, text, tba.ModeMarkdown, buttons)
+}
+
+// SendInlineKeyboardMessageWithParseMode sends a keyboard message using the given parse mode
+// parseMode is one of "", "Markdown", "MarkdownV2" or "HTML"
+func (bc *BotClient) SendInlineKeyboardMessageWithParseMode(chatID int64, text, parseMode string, buttons []InlineKeyboardButton) (int, error) {
+	return bc.SendInlineKeyboardMessageWithParseModeCtx(context.Background(), chatID, text, parseMode, buttons)
+}
+
+// SendInlineKeyboardMessageWithParseModeCtx sends a keyboard message using the given parse mode, aborting if ctx is done
+func (bc *BotClient) SendInlineKeyboardMessageWithParseModeCtx(ctx context.Context, chatID int64, text, parseMode string, buttons []InlineKeyboardButton) (int, error) {
+	if !IsValidParseMode(parseMode) {
+		return 0, fmt.Errorf("unsupported parse mode: %q", parseMode)
+	}
 	if len(buttons) == 0 {
 		return 0, fmt.Errorf("at least one button is required")
 	}
 
-	// Create single row keyboard
-	row := make([]tba.InlineKeyboardButton, len(buttons))
-	for i, btn := range buttons {
-		row[i] = tba.NewInlineKeyboardButtonData(btn.Text, btn.Data)
+	keyboard, err := buildInlineKeyboard(buttons)
+	if err != nil {
+		return 0, err
 	}
-
-	keyboard := tba.NewInlineKeyboardMarkup(row)
 	keyboardPtr := &keyboard
 
 	msg := tba.NewMessage(chatID, text)
 	msg.ReplyMarkup = keyboardPtr
-	msg.ParseMode = "Markdown"
+	msg.ParseMode = parseMode
 
-	sent, err := bc.bot.Send(msg)
+	sent, err := bc.send(ctx, msg)
 	if err != nil {
 		return 0, fmt.Errorf("failed to send message with keyboard: %w", err)
 	}
@@ -95,23 +239,43 @@ func (bc *BotClient) SendInlineKeyboardMessage(chatID int64, text string, button
 	return sent.MessageID, nil
 }
 
+// IsValidParseMode reports whether parseMode is a Telegram parse mode, empty meaning plain text
+func IsValidParseMode(parseMode string) bool {
+	switch parseMode {
+	case "", tba.ModeMarkdown, tba.ModeMarkdownV2, tba.ModeHTML:
+		return true
+	default:
+		return false
+	}
+}
+
 // SendTwoButtonKeyboard sends a message with two buttons (Approve/Decline pattern)
 func (bc *BotClient) SendTwoButtonKeyboard(chatID int64, text string, approveData, declineData string) (int, error) {
+	return bc.SendTwoButtonKeyboardCtx(context.Background(), chatID, text, approveData, declineData)
+}
+
+// SendTwoButtonKeyboardCtx sends an Approve/Decline keyboard message, aborting if ctx is done
+func (bc *BotClient) SendTwoButtonKeyboardCtx(ctx context.Context, chatID int64, text string, approveData, declineData string) (int, error) {
 	buttons := []InlineKeyboardButton{
 		{Text: "✅ Approve", Data: approveData},
 		{Text: "❌ Decline", Data: declineData},
 	}
-	return bc.SendInlineKeyboardMessage(chatID, text, buttons)
+	return bc.SendInlineKeyboardMessageCtx(ctx, chatID, text, buttons)
 }
 
 // EditMessage edits an existing message
 func (bc *BotClient) EditMessage(chatID int64, messageID int, text string) error {
+	return bc.EditMessageCtx(context.Background(), chatID, messageID, text)
+}
+
+// EditMessageCtx edits an existing message, aborting if ctx is done
+func (bc *BotClient) EditMessageCtx(ctx context.Context, chatID int64, messageID int, text string) error {
 	msg := tba.NewEditMessageText(chatID, messageID, text)
 	msg.ParseMode = "Markdown"
 
-	_, err := bc.bot.Send(msg)
+	_, err := bc.send(ctx, msg)
 	if err != nil {
-		return fmt.Errorf("failed to edit message: %w", err)
+		return fmt.Errorf("failed to edit message: %w", normalizeMessageNotFound(err))
 	}
 
 	return nil
@@ -119,21 +283,24 @@ func (bc *BotClient) EditMessage(chatID int64, messageID int, text string) error
 
 // EditMessageWithKeyboard edits a message and adds a keyboard
 func (bc *BotClient) EditMessageWithKeyboard(chatID int64, messageID int, text string, buttons []InlineKeyboardButton) error {
-	row := make([]tba.InlineKeyboardButton, len(buttons))
-	for i, btn := range buttons {
-		row[i] = tba.NewInlineKeyboardButtonData(btn.Text, btn.Data)
-	}
+	return bc.EditMessageWithKeyboardCtx(context.Background(), chatID, messageID, text, buttons)
+}
 
-	keyboard := tba.NewInlineKeyboardMarkup(row)
+// EditMessageWithKeyboardCtx edits a message and adds a keyboard, aborting if ctx is done
+func (bc *BotClient) EditMessageWithKeyboardCtx(ctx context.Context, chatID int64, messageID int, text string, buttons []InlineKeyboardButton) error {
+	keyboard, err := buildInlineKeyboard(buttons)
+	if err != nil {
+		return err
+	}
 	keyboardPtr := &keyboard
 
 	msg := tba.NewEditMessageText(chatID, messageID, text)
 	msg.ReplyMarkup = keyboardPtr
 	msg.ParseMode = "Markdown"
 
-	_, err := bc.bot.Send(msg)
+	_, err = bc.send(ctx, msg)
 	if err != nil {
-		return fmt.Errorf("failed to edit message with keyboard: %w", err)
+		return fmt.Errorf("failed to edit message with keyboard: %w", normalizeMessageNotFound(err))
 	}
 
 	return nil
@@ -141,8 +308,13 @@ func (bc *BotClient) EditMessageWithKeyboard(chatID int64, messageID int, text s
 
 // AnswerCallbackQuery acknowledges a button click
 func (bc *BotClient) AnswerCallbackQuery(callbackQueryID, text string) error {
+	return bc.AnswerCallbackQueryCtx(context.Background(), callbackQueryID, text)
+}
+
+// AnswerCallbackQueryCtx acknowledges a button click, aborting if ctx is done
+func (bc *BotClient) AnswerCallbackQueryCtx(ctx context.Context, callbackQueryID, text string) error {
 	callback := tba.NewCallback(callbackQueryID, text)
-	_, err := bc.bot.Send(callback)
+	_, err := bc.send(ctx, callback)
 	if err != nil {
 		return fmt.Errorf("failed to answer callback: %w", err)
 	}
@@ -150,20 +322,81 @@ func (bc *BotClient) AnswerCallbackQuery(callbackQueryID, text string) error {
 	return nil
 }
 
+// AnswerCallbackQueryWithURL acknowledges a button click by opening url
+func (bc *BotClient) AnswerCallbackQueryWithURL(callbackQueryID, url string) error {
+	return bc.AnswerCallbackQueryWithURLCtx(context.Background(), callbackQueryID, url)
+}
+
+// AnswerCallbackQueryWithURLCtx acknowledges a button click by opening url, aborting if ctx is done
+func (bc *BotClient) AnswerCallbackQueryWithURLCtx(ctx context.Context, callbackQueryID, url string) error {
+	if url == "" {
+		return fmt.Errorf("callback URL is required")
+	}
+
+	callback := tba.NewCallback(callbackQueryID, "")
+	callback.URL = url
+	_, err := bc.send(ctx, callback)
+	if err != nil {
+		return fmt.Errorf("failed to answer callback with URL: %w", err)
+	}
+
+	return nil
+}
+
 // DeleteMessage deletes a message
 func (bc *BotClient) DeleteMessage(chatID int64, messageID int) error {
+	return bc.DeleteMessageCtx(context.Background(), chatID, messageID)
+}
+
+// DeleteMessageCtx deletes a message, aborting if ctx is done
+func (bc *BotClient) DeleteMessageCtx(ctx context.Context, chatID int64, messageID int) error {
 	msg := tba.NewDeleteMessage(chatID, messageID)
-	_, err := bc.bot.Send(msg)
+	_, err := bc.send(ctx, msg)
 	if err != nil {
-		return fmt.Errorf("failed to delete message: %w", err)
+		return fmt.Errorf("failed to delete message: %w", normalizeMessageNotFound(err))
 	}
 
 	return nil
 }
 
+// normalizeMessageNotFound maps Telegram "message to edit/delete not found" errors to ErrMessageNotFound
+func normalizeMessageNotFound(err error) error {
+	var apiErr *tba.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		return err
+	}
+
+	msg := strings.ToLower(apiErr.Message)
+	if strings.Contains(msg, "message to edit not found") || strings.Contains(msg, "message to delete not found") {
+		return fmt.Errorf("%w: %v", ErrMessageNotFound, err)
+	}
+
+	return err
+}
+
+// buildInlineKeyboard creates a single row keyboard, emitting URL or callback buttons
+func buildInlineKeyboard(buttons []InlineKeyboardButton) (tba.InlineKeyboardMarkup, error) {
+	row := make([]tba.InlineKeyboardButton, len(buttons))
+	for i, btn := range buttons {
+		switch {
+		case btn.Data != "" && btn.URL != "":
+			return tba.InlineKeyboardMarkup{}, fmt.Errorf("button %q must have either data or URL, not both", btn.Text)
+		case btn.URL != "":
+			row[i] = tba.NewInlineKeyboardButtonURL(btn.Text, btn.URL)
+		case btn.Data != "":
+			row[i] = tba.NewInlineKeyboardButtonData(btn.Text, btn.Data)
+		default:
+			return tba.InlineKeyboardMarkup{}, fmt.Errorf("button %q must have either data or URL", btn.Text)
+		}
+	}
+
+	return tba.NewInlineKeyboardMarkup(row), nil
+}
+
 // GetBot returns the underlying bot API client
 func (bc *BotClient) GetBot() *tba.BotAPI {
-	return bc.bot
+	bot, _ := bc.bot.(*tba.BotAPI)
+	return bot
 }
 
 // FormatCallbackData creates callback data string
@@ -173,20 +406,63 @@ func FormatCallbackData(action, reviewRequestID string) string {
 
 // ParseCallbackData parses callback data string
 func ParseCallbackData(data string) (action, reviewRequestID string, err error) {
+	parsed, err := ParseCallbackDataStruct(data)
+	if err != nil {
+		return "", "", err
+	}
+
+	return parsed.Action, parsed.ReviewRequestID, nil
+}
+
+// ParseCallbackDataStruct parses callback data string into models.TelegramCallbackData
+func ParseCallbackDataStruct(data string) (*models.TelegramCallbackData, error) {
 	// Expected format: "ACTION:uuid"
 	parts := splitData(data, 2)
 	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid callback data format: %s", data)
+		return nil, fmt.Errorf("invalid callback data format: %s", data)
 	}
 
-	action = parts[0]
-	reviewRequestID = parts[1]
-
+	action := parts[0]
 	if action != "APPROVE" && action != "DECLINE" {
-		return "", "", fmt.Errorf("invalid action: %s", action)
+		return nil, fmt.Errorf("invalid action: %s", action)
 	}
 
-	return action, reviewRequestID, nil
+	if !models.IsValidReviewID(parts[1]) {
+		return nil, fmt.Errorf("invalid review request id: %s", parts[1])
+	}
+
+	return &models.TelegramCallbackData{
+		Action:          action,
+		ReviewRequestID: parts[1],
+	}, nil
+}
+
+// markdownV2Replacer escapes every character reserved by Telegram MarkdownV2
+var markdownV2Replacer = strings.NewReplacer(
+	"\\", "\\\\",
+	"_", "\\_",
+	"*", "\\*",
+	"[", "\\[",
+	"]", "\\]",
+	"(", "\\(",
+	")", "\\)",
+	"~", "\\~",
+	"`", "\\`",
+	">", "\\>",
+	"#", "\\#",
+	"+", "\\+",
+	"-", "\\-",
+	"=", "\\=",
+	"|", "\\|",
+	"{", "\\{",
+	"}", "\\}",
+	".", "\\.",
+	"!", "\\!",
+)
+
+// EscapeMarkdownV2 escapes MarkdownV2 reserved characters so the string is rendered literally
+func EscapeMarkdownV2(s string) string {
+	return markdownV2Replacer.Replace(s)
 }
 
 // splitData is a helper to split strings
@@ -1,13 +1,16 @@
 package telegram
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	tba "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockBotAPI is a mock implementation of the Telegram Bot API
@@ -147,6 +150,186 @@ func TestSendPlainMessage(t *testing.T) {
 	t.Skip("Requires mock implementation of BotAPI interface - consider extracting interface for BotAPI")
 }
 
+// TestSendPlainMessage_RetriesOnRateLimit tests that a 429 response is retried after retry_after
+func TestSendPlainMessage_RetriesOnRateLimit(t *testing.T) {
+	mockAPI := new(MockBotAPI)
+	rateLimited := &tba.Error{
+		Code:               429,
+		Message:            "Too Many Requests: retry after 1",
+		ResponseParameters: tba.ResponseParameters{RetryAfter: 1},
+	}
+	mockAPI.On("Send", mock.Anything).Return(nil, rateLimited).Once()
+	mockAPI.On("Send", mock.Anything).Return(tba.Message{MessageID: 42}, nil).Once()
+
+	client := newBotClientWithAPI(mockAPI)
+	client.SetRetryPolicy(DefaultMaxRetries, time.Millisecond)
+
+	err := client.SendPlainMessage(12345, "hello")
+
+	assert.NoError(t, err)
+	mockAPI.AssertNumberOfCalls(t, "Send", 2)
+}
+
+// TestSendPlainMessage_RateLimitRetriesExhausted tests that the error is returned once retries run out
+func TestSendPlainMessage_RateLimitRetriesExhausted(t *testing.T) {
+	mockAPI := new(MockBotAPI)
+	rateLimited := &tba.Error{
+		Code:               429,
+		Message:            "Too Many Requests: retry after 1",
+		ResponseParameters: tba.ResponseParameters{RetryAfter: 1},
+	}
+	mockAPI.On("Send", mock.Anything).Return(nil, rateLimited)
+
+	client := newBotClientWithAPI(mockAPI)
+	client.SetRetryPolicy(2, time.Millisecond)
+
+	err := client.SendPlainMessage(12345, "hello")
+
+	assert.Error(t, err)
+	assert.ErrorIs(t, err, rateLimited)
+	mockAPI.AssertNumberOfCalls(t, "Send", 3)
+}
+
+// TestSendPlainMessage_NoRetryOnOtherErrors tests that non-429 errors are returned immediately
+func TestSendPlainMessage_NoRetryOnOtherErrors(t *testing.T) {
+	mockAPI := new(MockBotAPI)
+	mockAPI.On("Send", mock.Anything).Return(nil, &tba.Error{Code: 400, Message: "Bad Request"})
+
+	client := newBotClientWithAPI(mockAPI)
+	client.SetRetryPolicy(DefaultMaxRetries, time.Millisecond)
+
+	err := client.SendPlainMessage(12345, "hello")
+
+	assert.Error(t, err)
+	mockAPI.AssertNumberOfCalls(t, "Send", 1)
+}
+
+// TestSendMessageBatch tests that failures are collected per chat without aborting the batch
+func TestSendMessageBatch(t *testing.T) {
+	mockAPI := new(MockBotAPI)
+	forChat := func(chatID int64) interface{} {
+		return mock.MatchedBy(func(c tba.Chattable) bool {
+			msg, ok := c.(tba.MessageConfig)
+			return ok && msg.ChatID == chatID
+		})
+	}
+	rateLimited := &tba.Error{Code: 429, Message: "Too Many Requests", ResponseParameters: tba.ResponseParameters{RetryAfter: 1}}
+	blocked := &tba.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}
+
+	mockAPI.On("Send", forChat(1)).Return(tba.Message{MessageID: 1}, nil)
+	mockAPI.On("Send", forChat(2)).Return(nil, blocked)
+	mockAPI.On("Send", forChat(3)).Return(nil, rateLimited).Once()
+	mockAPI.On("Send", forChat(3)).Return(tba.Message{MessageID: 3}, nil).Once()
+	mockAPI.On("Send", forChat(4)).Return(nil, fmt.Errorf("network down"))
+
+	client := newBotClientWithAPI(mockAPI)
+	client.SetRetryPolicy(DefaultMaxRetries, time.Millisecond)
+
+	sent, failures := client.SendMessageBatch([]int64{1, 2, 3, 4}, "notice")
+
+	assert.Equal(t, []int64{1, 3}, sent)
+	require.Len(t, failures, 2)
+	assert.ErrorIs(t, failures[2], blocked)
+	assert.Contains(t, failures[4].Error(), "network down")
+}
+
+// TestCtxMethods_CancelledContext tests that a cancelled context aborts every send without calling the API
+func TestCtxMethods_CancelledContext(t *testing.T) {
+	buttons := []InlineKeyboardButton{{Text: "OK", Data: "APPROVE:123"}}
+
+	tests := []struct {
+		name string
+		call func(ctx context.Context, bc *BotClient) error
+	}{
+		{
+			name: "SendPlainMessageCtx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				return bc.SendPlainMessageCtx(ctx, 12345, "hello")
+			},
+		},
+		{
+			name: "SendPlainMessageV2Ctx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				return bc.SendPlainMessageV2Ctx(ctx, 12345, "hello")
+			},
+		},
+		{
+			name: "SendInlineKeyboardMessageCtx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				_, err := bc.SendInlineKeyboardMessageCtx(ctx, 12345, "hello", buttons)
+				return err
+			},
+		},
+		{
+			name: "SendTwoButtonKeyboardCtx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				_, err := bc.SendTwoButtonKeyboardCtx(ctx, 12345, "hello", "APPROVE:1", "DECLINE:1")
+				return err
+			},
+		},
+		{
+			name: "EditMessageCtx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				return bc.EditMessageCtx(ctx, 12345, 1, "hello")
+			},
+		},
+		{
+			name: "EditMessageWithKeyboardCtx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				return bc.EditMessageWithKeyboardCtx(ctx, 12345, 1, "hello", buttons)
+			},
+		},
+		{
+			name: "AnswerCallbackQueryCtx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				return bc.AnswerCallbackQueryCtx(ctx, "callback-id", "done")
+			},
+		},
+		{
+			name: "DeleteMessageCtx",
+			call: func(ctx context.Context, bc *BotClient) error {
+				return bc.DeleteMessageCtx(ctx, 12345, 1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockAPI := new(MockBotAPI)
+			client := newBotClientWithAPI(mockAPI)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := tt.call(ctx, client)
+
+			assert.ErrorIs(t, err, context.Canceled)
+			mockAPI.AssertNotCalled(t, "Send", mock.Anything)
+		})
+	}
+}
+
+// TestSendPlainMessageCtx_CancelledDuringRetryWait tests that cancellation interrupts the rate-limit wait
+func TestSendPlainMessageCtx_CancelledDuringRetryWait(t *testing.T) {
+	mockAPI := new(MockBotAPI)
+	rateLimited := &tba.Error{
+		Code:               429,
+		Message:            "Too Many Requests: retry after 60",
+		ResponseParameters: tba.ResponseParameters{RetryAfter: 60},
+	}
+	mockAPI.On("Send", mock.Anything).Return(nil, rateLimited)
+
+	client := newBotClientWithAPI(mockAPI)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := client.SendPlainMessageCtx(ctx, 12345, "hello")
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	mockAPI.AssertNumberOfCalls(t, "Send", 1)
+}
+
 // TestSendInlineKeyboardMessage tests the SendInlineKeyboardMessage method
 func TestSendInlineKeyboardMessage(t *testing.T) {
 	t.Skip("Requires mock implementation of BotAPI interface - consider extracting interface for BotAPI")
@@ -273,18 +456,22 @@ func TestParseCallbackData(t *testing.T) {
 			expectError:     false,
 		},
 		{
-			name:            "Valid approve callback with simple ID",
-			data:            "APPROVE:12345",
-			expectedAction:  "APPROVE",
-			expectedID:      "12345",
-			expectError:     false,
+			name:          "Invalid ID - simple approve ID",
+			data:          "APPROVE:12345",
+			expectError:   true,
+			errorContains: "invalid review request id",
 		},
 		{
-			name:            "Valid decline callback with simple ID",
-			data:            "DECLINE:67890",
-			expectedAction:  "DECLINE",
-			expectedID:      "67890",
-			expectError:     false,
+			name:          "Invalid ID - simple decline ID",
+			data:          "DECLINE:67890",
+			expectError:   true,
+			errorContains: "invalid review request id",
+		},
+		{
+			name:          "Invalid ID - UUID without dashes",
+			data:          "APPROVE:550e8400e29b41d4a716446655440000",
+			expectError:   true,
+			errorContains: "invalid review request id",
 		},
 		{
 			name:          "Invalid format - missing action (no colon)",
@@ -295,9 +482,8 @@ func TestParseCallbackData(t *testing.T) {
 		{
 			name:          "Invalid format - missing ID",
 			data:          "APPROVE:",
-			expectedAction: "APPROVE",
-			expectedID:    "",
-			expectError:   false, // This is actually valid - action is present, ID is empty
+			expectError:   true,
+			errorContains: "invalid review request id",
 		},
 		{
 			name:          "Invalid action",
@@ -332,25 +518,22 @@ func TestParseCallbackData(t *testing.T) {
 			errorContains: "invalid action",
 		},
 		{
-			name:            "Multiple colons in ID - should preserve them",
-			data:            "APPROVE:550e8400:e29b-41d4-a716-446655440000",
-			expectedAction:  "APPROVE",
-			expectedID:      "550e8400:e29b-41d4-a716-446655440000",
-			expectError:     false,
+			name:          "Multiple colons in ID - not a UUID",
+			data:          "APPROVE:550e8400:e29b-41d4-a716-446655440000",
+			expectError:   true,
+			errorContains: "invalid review request id",
 		},
 		{
-			name:            "Multiple colons in ID - decline",
-			data:            "DECLINE:part1:part2:part3",
-			expectedAction:  "DECLINE",
-			expectedID:      "part1:part2:part3",
-			expectError:     false,
+			name:          "Multiple colons in ID - decline",
+			data:          "DECLINE:part1:part2:part3",
+			expectError:   true,
+			errorContains: "invalid review request id",
 		},
 		{
-			name:            "Special characters in ID",
-			data:            "APPROVE:id-with_special.chars-123",
-			expectedAction:  "APPROVE",
-			expectedID:      "id-with_special.chars-123",
-			expectError:     false,
+			name:          "Special characters in ID",
+			data:          "APPROVE:id-with_special.chars-123",
+			expectError:   true,
+			errorContains: "invalid review request id",
 		},
 		{
 			name:          "Action with numbers only",
@@ -384,6 +567,42 @@ func TestParseCallbackData(t *testing.T) {
 	}
 }
 
+// TestParseCallbackDataStruct tests that the struct fields match the parsed action and ID
+func TestParseCallbackDataStruct(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		expectError bool
+	}{
+		{name: "Approve with UUID", data: "APPROVE:550e8400-e29b-41d4-a716-446655440000"},
+		{name: "Decline with UUID", data: "DECLINE:550e8400-e29b-41d4-a716-446655440000"},
+		{name: "ID containing colons", data: "APPROVE:part1:part2", expectError: true},
+		{name: "Non-UUID ID", data: "DECLINE:12345", expectError: true},
+		{name: "Invalid action", data: "DELETE:123", expectError: true},
+		{name: "Missing separator", data: "APPROVE", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := ParseCallbackDataStruct(tt.data)
+			action, id, tupleErr := ParseCallbackData(tt.data)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, parsed)
+				assert.Error(t, tupleErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, tupleErr)
+			assert.Equal(t, action, parsed.Action)
+			assert.Equal(t, id, parsed.ReviewRequestID)
+			assert.Equal(t, tt.data, FormatCallbackData(parsed.Action, parsed.ReviewRequestID))
+		})
+	}
+}
+
 // TestSplitData tests the splitData helper function
 func TestSplitData(t *testing.T) {
 	tests := []struct {
@@ -546,6 +765,188 @@ func TestInlineKeyboardButton(t *testing.T) {
 	}
 }
 
+// TestBuildInlineKeyboard tests building keyboards from data and URL buttons
+func TestBuildInlineKeyboard(t *testing.T) {
+	t.Run("Data button", func(t *testing.T) {
+		keyboard, err := buildInlineKeyboard([]InlineKeyboardButton{
+			{Text: "✅ Approve", Data: "APPROVE:123"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, keyboard.InlineKeyboard, 1)
+		require.Len(t, keyboard.InlineKeyboard[0], 1)
+		btn := keyboard.InlineKeyboard[0][0]
+		require.NotNil(t, btn.CallbackData)
+		assert.Equal(t, "APPROVE:123", *btn.CallbackData)
+		assert.Nil(t, btn.URL)
+	})
+
+	t.Run("URL button", func(t *testing.T) {
+		keyboard, err := buildInlineKeyboard([]InlineKeyboardButton{
+			{Text: "📅 Calendar", URL: "https://edu.21-school.ru/calendar"},
+		})
+
+		require.NoError(t, err)
+		btn := keyboard.InlineKeyboard[0][0]
+		require.NotNil(t, btn.URL)
+		assert.Equal(t, "https://edu.21-school.ru/calendar", *btn.URL)
+		assert.Nil(t, btn.CallbackData)
+	})
+
+	t.Run("Mixed buttons keep order", func(t *testing.T) {
+		keyboard, err := buildInlineKeyboard([]InlineKeyboardButton{
+			{Text: "✅ Approve", Data: "APPROVE:123"},
+			{Text: "📅 Calendar", URL: "https://edu.21-school.ru/calendar"},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, keyboard.InlineKeyboard[0], 2)
+		assert.NotNil(t, keyboard.InlineKeyboard[0][0].CallbackData)
+		assert.NotNil(t, keyboard.InlineKeyboard[0][1].URL)
+	})
+
+	t.Run("Both data and URL set", func(t *testing.T) {
+		_, err := buildInlineKeyboard([]InlineKeyboardButton{
+			{Text: "Broken", Data: "APPROVE:123", URL: "https://edu.21-school.ru/calendar"},
+		})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not both")
+	})
+
+	t.Run("Neither data nor URL set", func(t *testing.T) {
+		_, err := buildInlineKeyboard([]InlineKeyboardButton{
+			{Text: "Empty"},
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+// TestSendInlineKeyboardMessage_InvalidButton tests that an invalid button is rejected before sending
+func TestSendInlineKeyboardMessage_InvalidButton(t *testing.T) {
+	mockAPI := new(MockBotAPI)
+	client := newBotClientWithAPI(mockAPI)
+
+	_, err := client.SendInlineKeyboardMessage(12345, "hello", []InlineKeyboardButton{
+		{Text: "Broken", Data: "APPROVE:123", URL: "https://edu.21-school.ru/calendar"},
+	})
+
+	assert.Error(t, err)
+	mockAPI.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+// TestSendInlineKeyboardMessageWithParseMode tests that the parse mode is passed through to Telegram
+func TestSendInlineKeyboardMessageWithParseMode(t *testing.T) {
+	buttons := []InlineKeyboardButton{{Text: "OK", Data: "APPROVE:123"}}
+
+	for _, parseMode := range []string{"", tba.ModeMarkdown, tba.ModeMarkdownV2, tba.ModeHTML} {
+		t.Run("mode "+parseMode, func(t *testing.T) {
+			mockAPI := new(MockBotAPI)
+			mockAPI.On("Send", mock.MatchedBy(func(c tba.Chattable) bool {
+				msg, ok := c.(tba.MessageConfig)
+				return ok && msg.ParseMode == parseMode
+			})).Return(tba.Message{MessageID: 7}, nil).Once()
+
+			client := newBotClientWithAPI(mockAPI)
+			id, err := client.SendInlineKeyboardMessageWithParseMode(12345, "<b>hi</b>", parseMode, buttons)
+
+			require.NoError(t, err)
+			assert.Equal(t, 7, id)
+			mockAPI.AssertExpectations(t)
+		})
+	}
+
+	t.Run("default method keeps Markdown", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+		mockAPI.On("Send", mock.MatchedBy(func(c tba.Chattable) bool {
+			msg, ok := c.(tba.MessageConfig)
+			return ok && msg.ParseMode == tba.ModeMarkdown
+		})).Return(tba.Message{MessageID: 8}, nil).Once()
+
+		client := newBotClientWithAPI(mockAPI)
+		_, err := client.SendInlineKeyboardMessage(12345, "*hi*", buttons)
+
+		require.NoError(t, err)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("unsupported mode is rejected", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+		client := newBotClientWithAPI(mockAPI)
+
+		_, err := client.SendInlineKeyboardMessageWithParseMode(12345, "hi", "markdown", buttons)
+
+		assert.Error(t, err)
+		mockAPI.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
+
+// TestMessageNotFoundNormalization tests that Telegram "message not found" errors map to ErrMessageNotFound
+func TestMessageNotFoundNormalization(t *testing.T) {
+	t.Run("delete of missing message", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+		mockAPI.On("Send", mock.Anything).Return(nil, &tba.Error{Code: 400, Message: "Bad Request: message to delete not found"})
+
+		err := newBotClientWithAPI(mockAPI).DeleteMessage(12345, 1)
+
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+	})
+
+	t.Run("edit of missing message", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+		mockAPI.On("Send", mock.Anything).Return(nil, &tba.Error{Code: 400, Message: "Bad Request: message to edit not found"})
+
+		err := newBotClientWithAPI(mockAPI).EditMessage(12345, 1, "updated")
+
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+	})
+
+	t.Run("edit with keyboard of missing message", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+		mockAPI.On("Send", mock.Anything).Return(nil, &tba.Error{Code: 400, Message: "Bad Request: message to edit not found"})
+
+		err := newBotClientWithAPI(mockAPI).EditMessageWithKeyboard(12345, 1, "updated", []InlineKeyboardButton{{Text: "OK", Data: "APPROVE:1"}})
+
+		assert.ErrorIs(t, err, ErrMessageNotFound)
+	})
+
+	t.Run("other errors are not mapped", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+		mockAPI.On("Send", mock.Anything).Return(nil, &tba.Error{Code: 400, Message: "Bad Request: message can't be deleted"})
+
+		err := newBotClientWithAPI(mockAPI).DeleteMessage(12345, 1)
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrMessageNotFound)
+	})
+}
+
+// TestAnswerCallbackQueryWithURL tests that the URL is set on the callback config
+func TestAnswerCallbackQueryWithURL(t *testing.T) {
+	t.Run("sets URL", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+		mockAPI.On("Send", mock.MatchedBy(func(c tba.Chattable) bool {
+			cb, ok := c.(tba.CallbackConfig)
+			return ok && cb.CallbackQueryID == "query-1" && cb.URL == "https://t.me/review_bot?start=123"
+		})).Return(tba.Message{}, nil).Once()
+
+		err := newBotClientWithAPI(mockAPI).AnswerCallbackQueryWithURL("query-1", "https://t.me/review_bot?start=123")
+
+		assert.NoError(t, err)
+		mockAPI.AssertExpectations(t)
+	})
+
+	t.Run("rejects empty URL", func(t *testing.T) {
+		mockAPI := new(MockBotAPI)
+
+		err := newBotClientWithAPI(mockAPI).AnswerCallbackQueryWithURL("query-1", "")
+
+		assert.Error(t, err)
+		mockAPI.AssertNotCalled(t, "Send", mock.Anything)
+	})
+}
+
 // TestMessageConfig tests the MessageConfig struct
 func TestMessageConfig(t *testing.T) {
 	tests := []struct {
@@ -842,8 +1243,11 @@ func TestEdgeCases(t *testing.T) {
 	t.Run("FormatCallbackData and ParseCallbackData roundtrip", func(t *testing.T) {
 		actions := []string{"APPROVE", "DECLINE"}
 		ids := []string{
-			"123",
 			"550e8400-e29b-41d4-a716-446655440000",
+			"6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		}
+		invalidIDs := []string{
+			"123",
 			"id-with_special.chars:123",
 			"",
 		}
@@ -857,6 +1261,10 @@ func TestEdgeCases(t *testing.T) {
 				assert.Equal(t, action, parsedAction)
 				assert.Equal(t, id, parsedID)
 			}
+			for _, id := range invalidIDs {
+				_, _, err := ParseCallbackData(FormatCallbackData(action, id))
+				assert.ErrorContains(t, err, "invalid review request id")
+			}
 		}
 	})
 
@@ -874,6 +1282,46 @@ func TestEdgeCases(t *testing.T) {
 	})
 }
 
+// TestEscapeMarkdownV2 tests the EscapeMarkdownV2 helper
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Underscore", input: "_", expected: "\\_"},
+		{name: "Asterisk", input: "*", expected: "\\*"},
+		{name: "Open bracket", input: "[", expected: "\\["},
+		{name: "Close bracket", input: "]", expected: "\\]"},
+		{name: "Open parenthesis", input: "(", expected: "\\("},
+		{name: "Close parenthesis", input: ")", expected: "\\)"},
+		{name: "Tilde", input: "~", expected: "\\~"},
+		{name: "Backtick", input: "`", expected: "\\`"},
+		{name: "Greater than", input: ">", expected: "\\>"},
+		{name: "Hash", input: "#", expected: "\\#"},
+		{name: "Plus", input: "+", expected: "\\+"},
+		{name: "Minus", input: "-", expected: "\\-"},
+		{name: "Equals", input: "=", expected: "\\="},
+		{name: "Pipe", input: "|", expected: "\\|"},
+		{name: "Open brace", input: "{", expected: "\\{"},
+		{name: "Close brace", input: "}", expected: "\\}"},
+		{name: "Dot", input: ".", expected: "\\."},
+		{name: "Exclamation", input: "!", expected: "\\!"},
+		{name: "Backslash", input: "\\", expected: "\\\\"},
+		{name: "Already safe string", input: "Hello world 123", expected: "Hello world 123"},
+		{name: "Empty string", input: "", expected: ""},
+		{name: "Reviewer login", input: "john_doe", expected: "john\\_doe"},
+		{name: "Project name", input: "C2_s21_stringplus-1.0", expected: "C2\\_s21\\_stringplus\\-1\\.0"},
+		{name: "Unicode is untouched", input: "✅ Привет", expected: "✅ Привет"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, EscapeMarkdownV2(tt.input))
+		})
+	}
+}
+
 // TestIntegration_FormatParseRoundtrip tests the integration between FormatCallbackData and ParseCallbackData
 func TestIntegration_FormatParseRoundtrip(t *testing.T) {
 	testCases := []struct {
@@ -882,10 +1330,8 @@ func TestIntegration_FormatParseRoundtrip(t *testing.T) {
 	}{
 		{"APPROVE", "550e8400-e29b-41d4-a716-446655440000"},
 		{"DECLINE", "550e8400-e29b-41d4-a716-446655440000"},
-		{"APPROVE", "12345"},
-		{"DECLINE", "67890"},
-		{"APPROVE", "id-with_special.chars:123"},
-		{"DECLINE", "part1:part2:part3"},
+		{"APPROVE", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"DECLINE", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
 	}
 
 	for _, tc := range testCases {
@@ -1,12 +1,51 @@
 package timeutil
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 )
 
+// Clock supplies the current time to the functions in this package
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is the default Clock backed by time.Now
+type realClock struct{}
+
+// Now returns the wall-clock time
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// clock holds the active Clock; nil means realClock
+var clock atomic.Pointer[Clock]
+
+// SetClock replaces the package clock, typically with a fixed one in tests; nil restores the real clock
+func SetClock(c Clock) {
+	if c == nil {
+		clock.Store(nil)
+		return
+	}
+	clock.Store(&c)
+}
+
+// now returns the current time from the active clock
+func now() time.Time {
+	if c := clock.Load(); c != nil {
+		return (*c).Now()
+	}
+	return realClock{}.Now()
+}
+
 // NowUTC returns current time in UTC
 func NowUTC() time.Time {
-	return time.Now().UTC()
+	return now().UTC()
 }
 
 // ToUTC converts any time to UTC
@@ -16,22 +55,40 @@ func ToUTC(t time.Time) time.Time {
 
 // FormatForMessage formats time for Telegram messages
 func FormatForMessage(t time.Time) string {
-	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	return FormatForMessageIn(t, time.UTC)
+}
+
+// FormatForMessageIn formats time for Telegram messages in the given location (UTC if nil)
+func FormatForMessageIn(t time.Time, loc *time.Location) string {
+	return t.In(locationOrUTC(loc)).Format("2006-01-02 15:04:05 MST")
 }
 
 // FormatShort formats time in short readable format
 func FormatShort(t time.Time) string {
-	return t.UTC().Format("Jan 2 15:04 UTC")
+	return FormatShortIn(t, time.UTC)
+}
+
+// FormatShortIn formats time in short readable format in the given location (UTC if nil)
+func FormatShortIn(t time.Time, loc *time.Location) string {
+	return t.In(locationOrUTC(loc)).Format("Jan 2 15:04 MST")
+}
+
+// locationOrUTC returns loc, or UTC when loc is nil
+func locationOrUTC(loc *time.Location) *time.Location {
+	if loc == nil {
+		return time.UTC
+	}
+	return loc
 }
 
 // IsExpired checks if a deadline has passed
 func IsExpired(deadline time.Time) bool {
-	return time.Now().After(deadline)
+	return now().After(deadline)
 }
 
 // MinutesUntil returns minutes until a time (negative if past)
 func MinutesUntil(t time.Time) int {
-	duration := time.Until(t)
+	duration := t.Sub(now())
 	return int(duration.Minutes())
 }
 
@@ -50,6 +107,35 @@ func DurationInMinutes(d time.Duration) int {
 	return int(d.Minutes())
 }
 
+// HumanizeDuration formats a duration compactly: "45m", "1h30m", "2h"
+// Durations under a minute render as "just now", negative ones as "<form> ago"
+func HumanizeDuration(d time.Duration) string {
+	negative := d < 0
+	if negative {
+		d = -d
+	}
+	if d < time.Minute {
+		return "just now"
+	}
+
+	hours := int64(d / time.Hour)
+	minutes := int64((d % time.Hour) / time.Minute)
+
+	buf := make([]byte, 0, 16)
+	if hours > 0 {
+		buf = strconv.AppendInt(buf, hours, 10)
+		buf = append(buf, 'h')
+	}
+	if minutes > 0 {
+		buf = strconv.AppendInt(buf, minutes, 10)
+		buf = append(buf, 'm')
+	}
+	if negative {
+		buf = append(buf, " ago"...)
+	}
+	return string(buf)
+}
+
 // ToUnixMillis converts time to Unix milliseconds
 func ToUnixMillis(t time.Time) int64 {
 	return t.UnixMilli()
@@ -65,6 +151,24 @@ func ToUnixSeconds32(t time.Time) uint32 {
 	return uint32(t.Unix())
 }
 
+// flexibleTimeLayouts are the layouts tried by ParseFlexibleTime, in order
+var flexibleTimeLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+}
+
+// ParseFlexibleTime parses a timestamp in any of the supported layouts and returns it in UTC
+// Layouts without a zone are interpreted as UTC
+func ParseFlexibleTime(s string) (time.Time, error) {
+	for _, layout := range flexibleTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("failed to parse time %q: tried layouts %s", s, strings.Join(flexibleTimeLayouts, ", "))
+}
+
 // FromUnixMillis converts Unix milliseconds to time
 func FromUnixMillis(ms int64) time.Time {
 	return time.UnixMilli(ms).UTC()
@@ -85,17 +189,84 @@ func CalculateDecisionDeadline(reviewStartTime time.Time, shiftMinutes int) time
 	return reviewStartTime.Add(-time.Duration(shiftMinutes) * time.Minute)
 }
 
+// CalculateDecisionDeadlineClamped calculates the decision deadline but never earlier than now+minLeadMinutes
+func CalculateDecisionDeadlineClamped(reviewStartTime time.Time, shiftMinutes int, minLeadMinutes int) time.Time {
+	deadline := CalculateDecisionDeadline(reviewStartTime, shiftMinutes)
+	earliest := now().Add(time.Duration(minLeadMinutes) * time.Minute)
+	if deadline.Before(earliest) {
+		return earliest
+	}
+	return deadline
+}
+
 // CalculateNonWhitelistCancelTime calculates when to auto-cancel non-whitelisted review
 func CalculateNonWhitelistCancelTime(delayMinutes int) time.Time {
-	return time.Now().Add(time.Duration(delayMinutes) * time.Minute)
+	return now().Add(time.Duration(delayMinutes) * time.Minute)
+}
+
+// CancelTimeForUser calculates when to cancel a non-whitelisted review using the user's
+// NonWhitelistCancelDelayMinutes; nil settings fall back to the defaults
+func CancelTimeForUser(settings *models.UserSettings) time.Time {
+	if settings == nil {
+		settings = models.DefaultUserSettings("")
+	}
+	return CalculateNonWhitelistCancelTime(int(settings.NonWhitelistCancelDelayMinutes))
 }
 
 // ShouldShiftSlot checks if slot should be shifted
 func ShouldShiftSlot(slotStartTime time.Time, thresholdMinutes int) bool {
-	thresholdFromNow := time.Now().Add(time.Duration(thresholdMinutes) * time.Minute)
+	thresholdFromNow := now().Add(time.Duration(thresholdMinutes) * time.Minute)
 	return thresholdFromNow.After(slotStartTime) || thresholdFromNow.Equal(slotStartTime)
 }
 
+// ComputeShiftedSlot moves both ends of a slot by shiftMinutes, preserving its duration
+func ComputeShiftedSlot(slotStart, slotEnd time.Time, shiftMinutes int) (newStart, newEnd time.Time) {
+	shift := time.Duration(shiftMinutes) * time.Minute
+	return slotStart.Add(shift), slotEnd.Add(shift)
+}
+
+// ShouldShiftAndCompute checks the user's shift threshold and returns the shifted slot when it applies
+// Nil settings fall back to the defaults; the original slot is returned when no shift is needed
+func ShouldShiftAndCompute(slotStart, slotEnd time.Time, settings *models.UserSettings) (newStart, newEnd time.Time, shifted bool) {
+	if settings == nil {
+		settings = models.DefaultUserSettings("")
+	}
+	if !ShouldShiftSlot(slotStart, int(settings.SlotShiftThresholdMinutes)) {
+		return slotStart, slotEnd, false
+	}
+	newStart, newEnd = ComputeShiftedSlot(slotStart, slotEnd, int(settings.SlotShiftDurationMinutes))
+	return newStart, newEnd, true
+}
+
+// IsWithinBusinessHours checks if t falls within [startHour, endHour) in the given location (UTC if nil)
+// A window with startHour > endHour spans midnight; equal hours mean the whole day
+func IsWithinBusinessHours(t time.Time, startHour, endHour int, loc *time.Location) bool {
+	if startHour == endHour {
+		return true
+	}
+
+	hour := t.In(locationOrUTC(loc)).Hour()
+	if startHour < endHour {
+		return hour >= startHour && hour < endHour
+	}
+	return hour >= startHour || hour < endHour
+}
+
+// NextBusinessTime returns t if it is within business hours, otherwise the next opening time
+// The result is always in UTC
+func NextBusinessTime(t time.Time, startHour, endHour int, loc *time.Location) time.Time {
+	if IsWithinBusinessHours(t, startHour, endHour, loc) {
+		return t.UTC()
+	}
+
+	local := t.In(locationOrUTC(loc))
+	opening := time.Date(local.Year(), local.Month(), local.Day(), startHour, 0, 0, 0, local.Location())
+	if !opening.After(local) {
+		opening = time.Date(local.Year(), local.Month(), local.Day()+1, startHour, 0, 0, 0, local.Location())
+	}
+	return opening.UTC()
+}
+
 // CalculateSlotDuration returns slot duration in minutes
 func CalculateSlotDuration(start, end time.Time) int {
 	return int(end.Sub(start).Minutes())
@@ -6,6 +6,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 )
 
 func TestNowUTC(t *testing.T) {
@@ -118,6 +120,53 @@ func TestFormatShort(t *testing.T) {
 	}
 }
 
+func TestFormatForMessageIn(t *testing.T) {
+	input := time.Date(2025, 1, 8, 14, 30, 0, 0, time.UTC)
+
+	t.Run("New York winter time", func(t *testing.T) {
+		loc := mustLoadLocation(t, "America/New_York")
+		assert.Equal(t, "2025-01-08 09:30:00 EST", FormatForMessageIn(input, loc))
+	})
+
+	t.Run("New York summer time", func(t *testing.T) {
+		loc := mustLoadLocation(t, "America/New_York")
+		summer := time.Date(2025, 7, 8, 14, 30, 0, 0, time.UTC)
+		assert.Equal(t, "2025-07-08 10:30:00 EDT", FormatForMessageIn(summer, loc))
+	})
+
+	t.Run("Tokyo crosses midnight", func(t *testing.T) {
+		loc := mustLoadLocation(t, "Asia/Tokyo")
+		late := time.Date(2025, 1, 8, 20, 0, 0, 0, time.UTC)
+		assert.Equal(t, "2025-01-09 05:00:00 JST", FormatForMessageIn(late, loc))
+	})
+
+	t.Run("Nil location defaults to UTC", func(t *testing.T) {
+		assert.Equal(t, "2025-01-08 14:30:00 UTC", FormatForMessageIn(input, nil))
+	})
+
+	t.Run("Matches FormatForMessage for UTC", func(t *testing.T) {
+		assert.Equal(t, FormatForMessage(input), FormatForMessageIn(input, time.UTC))
+	})
+}
+
+func TestFormatShortIn(t *testing.T) {
+	input := time.Date(2025, 1, 8, 14, 30, 0, 0, time.UTC)
+
+	t.Run("New York", func(t *testing.T) {
+		loc := mustLoadLocation(t, "America/New_York")
+		assert.Equal(t, "Jan 8 09:30 EST", FormatShortIn(input, loc))
+	})
+
+	t.Run("Tokyo", func(t *testing.T) {
+		loc := mustLoadLocation(t, "Asia/Tokyo")
+		assert.Equal(t, "Jan 8 23:30 JST", FormatShortIn(input, loc))
+	})
+
+	t.Run("Nil location defaults to UTC", func(t *testing.T) {
+		assert.Equal(t, "Jan 8 14:30 UTC", FormatShortIn(input, nil))
+	})
+}
+
 func TestIsExpired(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -353,6 +402,33 @@ func TestDurationInMinutes(t *testing.T) {
 	}
 }
 
+func TestHumanizeDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    time.Duration
+		expected string
+	}{
+		{name: "Zero", input: 0, expected: "just now"},
+		{name: "Sub-minute", input: 45 * time.Second, expected: "just now"},
+		{name: "Negative sub-minute", input: -30 * time.Second, expected: "just now"},
+		{name: "Exactly one minute", input: time.Minute, expected: "1m"},
+		{name: "Minutes only", input: 45 * time.Minute, expected: "45m"},
+		{name: "Seconds are truncated", input: 45*time.Minute + 59*time.Second, expected: "45m"},
+		{name: "Exact hour", input: time.Hour, expected: "1h"},
+		{name: "Exact hours", input: 2 * time.Hour, expected: "2h"},
+		{name: "Hours and minutes", input: 90 * time.Minute, expected: "1h30m"},
+		{name: "More than a day", input: 26*time.Hour + 5*time.Minute, expected: "26h5m"},
+		{name: "Negative minutes", input: -15 * time.Minute, expected: "15m ago"},
+		{name: "Negative hours and minutes", input: -(time.Hour + 30*time.Minute), expected: "1h30m ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HumanizeDuration(tt.input))
+		})
+	}
+}
+
 func TestToUnixMillis(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -435,6 +511,57 @@ func TestToUnixSeconds(t *testing.T) {
 	}
 }
 
+func TestParseFlexibleTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Time
+	}{
+		{
+			name:     "RFC3339 UTC",
+			input:    "2025-01-08T14:30:00Z",
+			expected: time.Date(2025, 1, 8, 14, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "RFC3339 with offset",
+			input:    "2025-01-08T17:30:00+03:00",
+			expected: time.Date(2025, 1, 8, 14, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "RFC3339Nano",
+			input:    "2025-01-08T14:30:00.123456789Z",
+			expected: time.Date(2025, 1, 8, 14, 30, 0, 123456789, time.UTC),
+		},
+		{
+			name:     "Plain datetime is UTC",
+			input:    "2025-01-08 14:30:00",
+			expected: time.Date(2025, 1, 8, 14, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseFlexibleTime(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, time.UTC, result.Location())
+		})
+	}
+
+	t.Run("Garbage input", func(t *testing.T) {
+		_, err := ParseFlexibleTime("not a time")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not a time")
+		assert.Contains(t, err.Error(), time.RFC3339)
+		assert.Contains(t, err.Error(), "2006-01-02 15:04:05")
+	})
+
+	t.Run("Empty input", func(t *testing.T) {
+		_, err := ParseFlexibleTime("")
+		assert.Error(t, err)
+	})
+}
+
 func TestFromUnixMillis(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -665,6 +792,130 @@ func TestShouldShiftSlot(t *testing.T) {
 	}
 }
 
+func TestIsWithinBusinessHours(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*3600)
+
+	tests := []struct {
+		name      string
+		input     time.Time
+		startHour int
+		endHour   int
+		loc       *time.Location
+		expected  bool
+	}{
+		{
+			name:      "Inside hours in non-UTC location",
+			input:     time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC), // 12:00 MSK
+			startHour: 9,
+			endHour:   21,
+			loc:       moscow,
+			expected:  true,
+		},
+		{
+			name:      "Before opening in non-UTC location",
+			input:     time.Date(2025, 1, 8, 3, 0, 0, 0, time.UTC), // 06:00 MSK
+			startHour: 9,
+			endHour:   21,
+			loc:       moscow,
+			expected:  false,
+		},
+		{
+			name:      "After closing in non-UTC location",
+			input:     time.Date(2025, 1, 8, 19, 30, 0, 0, time.UTC), // 22:30 MSK
+			startHour: 9,
+			endHour:   21,
+			loc:       moscow,
+			expected:  false,
+		},
+		{
+			name:      "Closing hour is exclusive",
+			input:     time.Date(2025, 1, 8, 18, 0, 0, 0, time.UTC), // 21:00 MSK
+			startHour: 9,
+			endHour:   21,
+			loc:       moscow,
+			expected:  false,
+		},
+		{
+			name:      "Nil location uses UTC",
+			input:     time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC),
+			startHour: 9,
+			endHour:   21,
+			loc:       nil,
+			expected:  true,
+		},
+		{
+			name:      "Overnight window inside",
+			input:     time.Date(2025, 1, 8, 23, 0, 0, 0, time.UTC),
+			startHour: 22,
+			endHour:   6,
+			loc:       time.UTC,
+			expected:  true,
+		},
+		{
+			name:      "Overnight window outside",
+			input:     time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC),
+			startHour: 22,
+			endHour:   6,
+			loc:       time.UTC,
+			expected:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := IsWithinBusinessHours(tt.input, tt.startHour, tt.endHour, tt.loc)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestNextBusinessTime(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*3600)
+
+	tests := []struct {
+		name     string
+		input    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "Inside hours is unchanged",
+			input:    time.Date(2025, 1, 8, 9, 15, 0, 0, time.UTC), // 12:15 MSK
+			expected: time.Date(2025, 1, 8, 9, 15, 0, 0, time.UTC),
+		},
+		{
+			name:     "Before opening rolls to same-day opening",
+			input:    time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC), // 03:00 MSK
+			expected: time.Date(2025, 1, 8, 6, 0, 0, 0, time.UTC), // 09:00 MSK
+		},
+		{
+			name:     "After closing rolls to next-day opening",
+			input:    time.Date(2025, 1, 8, 19, 30, 0, 0, time.UTC), // 22:30 MSK
+			expected: time.Date(2025, 1, 9, 6, 0, 0, 0, time.UTC),   // 09:00 MSK next day
+		},
+		{
+			name:     "After local midnight rolls to same local day",
+			input:    time.Date(2025, 1, 8, 21, 30, 0, 0, time.UTC), // 00:30 MSK on Jan 9
+			expected: time.Date(2025, 1, 9, 6, 0, 0, 0, time.UTC),   // 09:00 MSK on Jan 9
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NextBusinessTime(tt.input, 9, 21, moscow)
+			assert.True(t, tt.expected.Equal(result), "expected %v, got %v", tt.expected, result)
+			assert.Equal(t, time.UTC, result.Location())
+		})
+	}
+
+	t.Run("Inside hours input in another zone is returned in UTC", func(t *testing.T) {
+		input := time.Date(2025, 1, 8, 12, 15, 0, 0, moscow)
+
+		result := NextBusinessTime(input, 9, 21, moscow)
+
+		assert.Equal(t, time.Date(2025, 1, 8, 9, 15, 0, 0, time.UTC), result)
+	})
+}
+
 func TestCalculateSlotDuration(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -737,3 +988,191 @@ func mustLoadLocation(t *testing.T, name string) *time.Location {
 	require.NoError(t, err)
 	return loc
 }
+
+func TestComputeShiftedSlot(t *testing.T) {
+	t.Run("preserves duration", func(t *testing.T) {
+		start := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+		end := start.Add(45 * time.Minute)
+
+		newStart, newEnd := ComputeShiftedSlot(start, end, 30)
+
+		assert.Equal(t, start.Add(30*time.Minute), newStart)
+		assert.Equal(t, end.Sub(start), newEnd.Sub(newStart))
+	})
+
+	t.Run("crosses day boundary", func(t *testing.T) {
+		start := time.Date(2025, 1, 8, 23, 40, 0, 0, time.UTC)
+		end := start.Add(time.Hour)
+
+		newStart, newEnd := ComputeShiftedSlot(start, end, 30)
+
+		assert.Equal(t, time.Date(2025, 1, 9, 0, 10, 0, 0, time.UTC), newStart)
+		assert.Equal(t, time.Date(2025, 1, 9, 1, 10, 0, 0, time.UTC), newEnd)
+		assert.Equal(t, time.Hour, newEnd.Sub(newStart))
+	})
+
+	t.Run("negative shift moves slot earlier", func(t *testing.T) {
+		start := time.Date(2025, 1, 9, 0, 15, 0, 0, time.UTC)
+		end := start.Add(30 * time.Minute)
+
+		newStart, newEnd := ComputeShiftedSlot(start, end, -30)
+
+		assert.Equal(t, time.Date(2025, 1, 8, 23, 45, 0, 0, time.UTC), newStart)
+		assert.Equal(t, 30*time.Minute, newEnd.Sub(newStart))
+	})
+}
+
+func TestShouldShiftAndCompute(t *testing.T) {
+	settings := models.DefaultUserSettings("testuser")
+	settings.SlotShiftThresholdMinutes = 25
+	settings.SlotShiftDurationMinutes = 15
+
+	t.Run("soon slot is shifted", func(t *testing.T) {
+		start := time.Now().Add(10 * time.Minute)
+		end := start.Add(30 * time.Minute)
+
+		newStart, newEnd, shifted := ShouldShiftAndCompute(start, end, settings)
+
+		assert.True(t, shifted)
+		assert.Equal(t, start.Add(15*time.Minute), newStart)
+		assert.Equal(t, 30*time.Minute, newEnd.Sub(newStart))
+	})
+
+	t.Run("distant slot is unchanged", func(t *testing.T) {
+		start := time.Now().Add(3 * time.Hour)
+		end := start.Add(30 * time.Minute)
+
+		newStart, newEnd, shifted := ShouldShiftAndCompute(start, end, settings)
+
+		assert.False(t, shifted)
+		assert.Equal(t, start, newStart)
+		assert.Equal(t, end, newEnd)
+	})
+
+	t.Run("nil settings use defaults", func(t *testing.T) {
+		defaults := models.DefaultUserSettings("")
+		start := time.Now().Add(time.Duration(defaults.SlotShiftThresholdMinutes-1) * time.Minute)
+		end := start.Add(30 * time.Minute)
+
+		newStart, _, shifted := ShouldShiftAndCompute(start, end, nil)
+
+		require.True(t, shifted)
+		assert.Equal(t, start.Add(time.Duration(defaults.SlotShiftDurationMinutes)*time.Minute), newStart)
+	})
+}
+
+func TestCalculateDecisionDeadlineClamped(t *testing.T) {
+	t.Run("far-future review is unchanged", func(t *testing.T) {
+		reviewStart := time.Now().Add(24 * time.Hour)
+
+		deadline := CalculateDecisionDeadlineClamped(reviewStart, 20, 5)
+
+		assert.Equal(t, CalculateDecisionDeadline(reviewStart, 20), deadline)
+	})
+
+	t.Run("imminent review is clamped", func(t *testing.T) {
+		before := time.Now()
+		reviewStart := before.Add(10 * time.Minute)
+
+		deadline := CalculateDecisionDeadlineClamped(reviewStart, 20, 5)
+
+		assert.False(t, deadline.Before(before.Add(5*time.Minute)))
+		assert.True(t, deadline.Before(time.Now().Add(5*time.Minute+time.Second)))
+	})
+
+	t.Run("started review is clamped", func(t *testing.T) {
+		before := time.Now()
+
+		deadline := CalculateDecisionDeadlineClamped(before.Add(-time.Hour), 20, 3)
+
+		assert.False(t, deadline.Before(before.Add(3*time.Minute)))
+	})
+}
+
+func TestCancelTimeForUser(t *testing.T) {
+	t.Run("custom settings delay", func(t *testing.T) {
+		settings := models.DefaultUserSettings("testuser")
+		settings.NonWhitelistCancelDelayMinutes = 12
+		settings.ResponseDeadlineShiftMinutes = 90
+
+		before := time.Now()
+		cancelAt := CancelTimeForUser(settings)
+		after := time.Now()
+
+		assert.False(t, cancelAt.Before(before.Add(12*time.Minute)))
+		assert.False(t, cancelAt.After(after.Add(12*time.Minute)))
+	})
+
+	t.Run("nil settings use defaults", func(t *testing.T) {
+		delay := time.Duration(models.DefaultUserSettings("").NonWhitelistCancelDelayMinutes) * time.Minute
+
+		before := time.Now()
+		cancelAt := CancelTimeForUser(nil)
+		after := time.Now()
+
+		assert.False(t, cancelAt.Before(before.Add(delay)))
+		assert.False(t, cancelAt.After(after.Add(delay)))
+	})
+}
+
+// fixedClock is a Clock that always returns the same instant
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// useFixedClock pins the package clock to at for the duration of the test
+func useFixedClock(t *testing.T, at time.Time) {
+	t.Helper()
+	SetClock(fixedClock(at))
+	t.Cleanup(func() { SetClock(nil) })
+}
+
+func TestFixedClock(t *testing.T) {
+	fixed := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	useFixedClock(t, fixed)
+
+	t.Run("NowUTC", func(t *testing.T) {
+		assert.Equal(t, fixed, NowUTC())
+	})
+
+	t.Run("IsExpired", func(t *testing.T) {
+		assert.True(t, IsExpired(fixed.Add(-time.Second)))
+		assert.False(t, IsExpired(fixed))
+		assert.False(t, IsExpired(fixed.Add(time.Second)))
+	})
+
+	t.Run("MinutesUntil", func(t *testing.T) {
+		assert.Equal(t, 30, MinutesUntil(fixed.Add(30*time.Minute)))
+		assert.Equal(t, -30, MinutesUntil(fixed.Add(-30*time.Minute)))
+		assert.Equal(t, 0, MinutesUntil(fixed))
+	})
+
+	t.Run("CalculateNonWhitelistCancelTime", func(t *testing.T) {
+		assert.Equal(t, fixed.Add(15*time.Minute), CalculateNonWhitelistCancelTime(15))
+	})
+
+	t.Run("CancelTimeForUser", func(t *testing.T) {
+		settings := models.DefaultUserSettings("testuser")
+		settings.NonWhitelistCancelDelayMinutes = 12
+		assert.Equal(t, fixed.Add(12*time.Minute), CancelTimeForUser(settings))
+	})
+
+	t.Run("CalculateDecisionDeadlineClamped", func(t *testing.T) {
+		assert.Equal(t, fixed.Add(5*time.Minute), CalculateDecisionDeadlineClamped(fixed.Add(10*time.Minute), 20, 5))
+		assert.Equal(t, fixed.Add(4*time.Hour), CalculateDecisionDeadlineClamped(fixed.Add(5*time.Hour), 60, 5))
+	})
+
+	t.Run("ShouldShiftSlot", func(t *testing.T) {
+		assert.True(t, ShouldShiftSlot(fixed.Add(25*time.Minute), 25))
+		assert.False(t, ShouldShiftSlot(fixed.Add(25*time.Minute+time.Second), 25))
+	})
+}
+
+func TestSetClockNilRestoresRealClock(t *testing.T) {
+	SetClock(fixedClock(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+	SetClock(nil)
+
+	assert.WithinDuration(t, time.Now(), NowUTC(), time.Second)
+}
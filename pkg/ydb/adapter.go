@@ -5,11 +5,15 @@ import (
 
 	"github.com/ydb-platform/ydb-go-sdk/v3/table"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table/result"
+
+	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 )
 
 // DatabaseAdapter implements the Database interface using global functions
 type DatabaseAdapter struct{}
 
+var _ Repository = (*DatabaseAdapter)(nil)
+
 // NewDatabaseAdapter creates a new DatabaseAdapter
 func NewDatabaseAdapter() *DatabaseAdapter {
 	return &DatabaseAdapter{}
@@ -35,3 +39,23 @@ func (d *DatabaseAdapter) DoTx(ctx context.Context, fn func(ctx context.Context,
 func (d *DatabaseAdapter) Close(ctx context.Context) error {
 	return CloseConnection(ctx)
 }
+
+// GetReviewRequestByID retrieves a review request by ID
+func (d *DatabaseAdapter) GetReviewRequestByID(ctx context.Context, id string) (*models.ReviewRequest, error) {
+	return getReviewRequestByID(ctx, d, id)
+}
+
+// CreateReviewRequest creates a new review request
+func (d *DatabaseAdapter) CreateReviewRequest(ctx context.Context, req *models.ReviewRequest) error {
+	return createReviewRequest(ctx, d, req)
+}
+
+// UpdateReviewRequestStatus updates a review request's status
+func (d *DatabaseAdapter) UpdateReviewRequestStatus(ctx context.Context, id, status string, decidedAt *uint32) error {
+	return updateReviewRequestStatus(ctx, d, id, status, decidedAt)
+}
+
+// GetActiveUsers retrieves all active users
+func (d *DatabaseAdapter) GetActiveUsers(ctx context.Context) ([]*models.User, error) {
+	return getActiveUsers(ctx, d)
+}
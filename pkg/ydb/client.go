@@ -2,8 +2,10 @@ package ydb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ydb-platform/ydb-go-sdk/v3"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table"
@@ -17,6 +19,9 @@ type YDBClient struct {
 	driver *ydb.Driver
 }
 
+// errDriverNotInitialized is returned by YDBClient calls made without an open driver
+var errDriverNotInitialized = errors.New("YDB driver is not initialized")
+
 // NewYDBClient creates a new YDB client from environment variables
 // Requires YDB_ENDPOINT and YDB_DATABASE environment variables to be set
 func NewYDBClient(ctx context.Context) (*YDBClient, error) {
@@ -43,6 +48,21 @@ func NewYDBClient(ctx context.Context) (*YDBClient, error) {
 
 // Query executes a query and returns the result set
 func (c *YDBClient) Query(ctx context.Context, sql string, params ...table.ParameterOption) (result.Result, error) {
+	start := time.Now()
+	res, err := c.query(ctx, sql, params...)
+	observeQuery(OpQuery, sql, start, err)
+	return res, err
+}
+
+// query executes a query on the client's driver
+func (c *YDBClient) query(ctx context.Context, sql string, params ...table.ParameterOption) (result.Result, error) {
+	if c.driver == nil {
+		return nil, errDriverNotInitialized
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
 	var res result.Result
 	err := c.driver.Table().Do(ctx, func(ctx context.Context, s table.Session) error {
 		_, r, err := s.Execute(ctx, table.DefaultTxControl(), sql, table.NewQueryParameters(params...))
@@ -62,6 +82,21 @@ func (c *YDBClient) Query(ctx context.Context, sql string, params ...table.Param
 
 // Exec executes a query that doesn't return results
 func (c *YDBClient) Exec(ctx context.Context, sql string, params ...table.ParameterOption) error {
+	start := time.Now()
+	err := c.exec(ctx, sql, params...)
+	observeQuery(OpExec, sql, start, err)
+	return err
+}
+
+// exec executes a query that doesn't return results on the client's driver
+func (c *YDBClient) exec(ctx context.Context, sql string, params ...table.ParameterOption) error {
+	if c.driver == nil {
+		return errDriverNotInitialized
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
 	err := c.driver.Table().Do(ctx, func(ctx context.Context, s table.Session) error {
 		_, _, err := s.Execute(ctx, table.DefaultTxControl(), sql, table.NewQueryParameters(params...))
 		return err
@@ -76,6 +111,18 @@ func (c *YDBClient) Exec(ctx context.Context, sql string, params ...table.Parame
 
 // DoTx executes a function within a transaction
 func (c *YDBClient) DoTx(ctx context.Context, fn func(ctx context.Context, tx table.TransactionActor) error) error {
+	start := time.Now()
+	err := c.doTx(ctx, fn)
+	observeQuery(OpDoTx, "", start, err)
+	return err
+}
+
+// doTx executes a function within a transaction on the client's driver
+func (c *YDBClient) doTx(ctx context.Context, fn func(ctx context.Context, tx table.TransactionActor) error) error {
+	if c.driver == nil {
+		return errDriverNotInitialized
+	}
+
 	err := c.driver.Table().DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
 		return fn(ctx, tx)
 	}, table.WithIdempotent())
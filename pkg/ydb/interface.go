@@ -5,6 +5,8 @@ import (
 
 	"github.com/ydb-platform/ydb-go-sdk/v3/table"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table/result"
+
+	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 )
 
 // Database defines the interface for YDB database operations
@@ -21,3 +23,21 @@ type Database interface {
 	// Close closes the database connection
 	Close(ctx context.Context) error
 }
+
+// Repository defines the data access operations services depend on
+// DatabaseAdapter implements it on top of its own Database methods
+type Repository interface {
+	Database
+
+	// GetReviewRequestByID retrieves a review request by ID
+	GetReviewRequestByID(ctx context.Context, id string) (*models.ReviewRequest, error)
+
+	// CreateReviewRequest creates a new review request
+	CreateReviewRequest(ctx context.Context, req *models.ReviewRequest) error
+
+	// UpdateReviewRequestStatus updates a review request's status
+	UpdateReviewRequestStatus(ctx context.Context, id, status string, decidedAt *uint32) error
+
+	// GetActiveUsers retrieves all active users
+	GetActiveUsers(ctx context.Context) ([]*models.User, error)
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table/result"
+
+	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 )
 
 // MockDatabase is a mock implementation of the Database interface
@@ -44,3 +46,45 @@ func (m *MockDatabase) Close(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
 }
+
+// MockRepository is a mock implementation of the Repository interface
+type MockRepository struct {
+	MockDatabase
+}
+
+var _ Repository = (*MockRepository)(nil)
+
+// NewMockRepository creates a new MockRepository instance
+func NewMockRepository() *MockRepository {
+	return &MockRepository{}
+}
+
+// GetReviewRequestByID retrieves a review request by ID
+func (m *MockRepository) GetReviewRequestByID(ctx context.Context, id string) (*models.ReviewRequest, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReviewRequest), args.Error(1)
+}
+
+// CreateReviewRequest creates a new review request
+func (m *MockRepository) CreateReviewRequest(ctx context.Context, req *models.ReviewRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+// UpdateReviewRequestStatus updates a review request's status
+func (m *MockRepository) UpdateReviewRequestStatus(ctx context.Context, id, status string, decidedAt *uint32) error {
+	args := m.Called(ctx, id, status, decidedAt)
+	return args.Error(0)
+}
+
+// GetActiveUsers retrieves all active users
+func (m *MockRepository) GetActiveUsers(ctx context.Context) ([]*models.User, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
@@ -2,18 +2,34 @@ package ydb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"slices"
+	"sync/atomic"
 	"time"
 
 	"github.com/ydb-platform/ydb-go-sdk/v3/table"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table/result"
+	"github.com/ydb-platform/ydb-go-sdk/v3/table/result/named"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table/types"
 
 	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
 	"github.com/flymedllva/ydb-go-qb/yscan"
 )
 
+// ErrMultipleRows is returned when a lookup by primary key matches more than one row
+var ErrMultipleRows = errors.New("multiple rows returned for single-row query")
+
+// ensureSingleRow returns ErrMultipleRows if res has another row after the current one
+func ensureSingleRow(res result.Result, lookup string) error {
+	if res.NextRow() {
+		return fmt.Errorf("%w: %s", ErrMultipleRows, lookup)
+	}
+	return nil
+}
+
 // optionalDatetime creates an optional Datetime value from a uint32 pointer
 func optionalDatetime(ts *uint32) types.Value {
 	if ts == nil {
@@ -68,6 +84,84 @@ func GetUserByTelegramChatID(ctx context.Context, telegramChatID int64) (*models
 	return nil, fmt.Errorf("user not found with telegram_chat_id %d", telegramChatID)
 }
 
+// GetUsersByTelegramChatIDs retrieves users for several Telegram chat IDs at once
+// Chat IDs without a user are absent from the returned map
+func GetUsersByTelegramChatIDs(ctx context.Context, telegramChatIDs []int64) (map[int64]*models.User, error) {
+	return getUsersByTelegramChatIDs(ctx, NewDatabaseAdapter(), telegramChatIDs)
+}
+
+// getUsersByTelegramChatIDs retrieves users for several Telegram chat IDs through db
+func getUsersByTelegramChatIDs(ctx context.Context, db Database, telegramChatIDs []int64) (map[int64]*models.User, error) {
+	ids := uniqueChatIDs(telegramChatIDs)
+	if len(ids) == 0 {
+		return map[int64]*models.User{}, nil
+	}
+
+	sql := TablePathPrefix("") + `
+		DECLARE $telegram_chat_ids AS List<Int64>;
+
+		SELECT reviewer_login, status, telegram_chat_id, created_at, last_auth_success_at, last_auth_failure_at
+		FROM users
+		WHERE telegram_chat_id IN $telegram_chat_ids;
+	`
+
+	values := make([]types.Value, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, types.Int64Value(id))
+	}
+
+	params := []table.ParameterOption{
+		table.ValueParam("$telegram_chat_ids", types.ListValue(values...)),
+	}
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users by telegram_chat_ids: %w", err)
+	}
+	defer res.Close()
+
+	var users []*models.User
+	for res.NextRow() {
+		var user models.User
+		err = yscan.ScanRow(&user, res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, &user)
+	}
+
+	return indexUsersByChatID(users, ids), nil
+}
+
+// uniqueChatIDs returns chat IDs without duplicates, preserving order
+func uniqueChatIDs(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	var unique []int64
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// indexUsersByChatID keys users by Telegram chat ID, keeping only requested IDs
+func indexUsersByChatID(users []*models.User, requested []int64) map[int64]*models.User {
+	wanted := make(map[int64]bool, len(requested))
+	for _, id := range requested {
+		wanted[id] = true
+	}
+
+	byChatID := make(map[int64]*models.User, len(users))
+	for _, user := range users {
+		if user != nil && wanted[user.TelegramChatID] {
+			byChatID[user.TelegramChatID] = user
+		}
+	}
+	return byChatID
+}
+
 // GetUserByReviewerLogin retrieves a user by their reviewer login
 func GetUserByReviewerLogin(ctx context.Context, reviewerLogin string) (*models.User, error) {
 	sql := TablePathPrefix("") + `
@@ -94,6 +188,9 @@ func GetUserByReviewerLogin(ctx context.Context, reviewerLogin string) (*models.
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
+		if err := ensureSingleRow(res, "users with reviewer_login "+reviewerLogin); err != nil {
+			return nil, err
+		}
 
 		return &user, nil
 	}
@@ -103,6 +200,10 @@ func GetUserByReviewerLogin(ctx context.Context, reviewerLogin string) (*models.
 
 // UpsertUser inserts or updates a user
 func UpsertUser(ctx context.Context, user *models.User) error {
+	if err := user.Validate(); err != nil {
+		return fmt.Errorf("invalid user: %w", err)
+	}
+
 	sql := TablePathPrefix("") + `
 		DECLARE $reviewer_login AS Utf8;
 		DECLARE $status AS Utf8;
@@ -164,35 +265,249 @@ func UpdateUserStatus(ctx context.Context, reviewerLogin, status string) error {
 	return Exec(ctx, sql, params...)
 }
 
-// GetActiveUsers retrieves all active users
-func GetActiveUsers(ctx context.Context) ([]*models.User, error) {
+// recordAuthSuccessSQL sets only last_auth_success_at of a user
+const recordAuthSuccessSQL = `
+		DECLARE $reviewer_login AS Utf8;
+		DECLARE $at AS Datetime;
+
+		UPDATE users
+		SET last_auth_success_at = $at
+		WHERE reviewer_login = $reviewer_login;
+	`
+
+// recordAuthFailureSQL sets only last_auth_failure_at of a user
+const recordAuthFailureSQL = `
+		DECLARE $reviewer_login AS Utf8;
+		DECLARE $at AS Datetime;
+
+		UPDATE users
+		SET last_auth_failure_at = $at
+		WHERE reviewer_login = $reviewer_login;
+	`
+
+// RecordAuthSuccess records the time of a user's last successful authentication
+func RecordAuthSuccess(ctx context.Context, reviewerLogin string, at int64) error {
+	return recordAuthTimestamp(ctx, NewDatabaseAdapter(), recordAuthSuccessSQL, reviewerLogin, at)
+}
+
+// RecordAuthFailure records the time of a user's last failed authentication
+func RecordAuthFailure(ctx context.Context, reviewerLogin string, at int64) error {
+	return recordAuthTimestamp(ctx, NewDatabaseAdapter(), recordAuthFailureSQL, reviewerLogin, at)
+}
+
+// GetLastAuthFailure returns the time of a user's last failed authentication, or nil if none
+func GetLastAuthFailure(ctx context.Context, reviewerLogin string) (*uint32, error) {
+	user, err := GetUserByReviewerLogin(ctx, reviewerLogin)
+	if err != nil {
+		return nil, err
+	}
+	return user.LastAuthFailureAt, nil
+}
+
+// recordAuthTimestamp runs a targeted auth timestamp update through db
+func recordAuthTimestamp(ctx context.Context, db Database, updateSQL, reviewerLogin string, at int64) error {
+	if reviewerLogin == "" {
+		return fmt.Errorf("reviewer login is required")
+	}
+	if at < 0 || at > math.MaxUint32 {
+		return fmt.Errorf("auth timestamp %d out of range", at)
+	}
+
+	params := []table.ParameterOption{
+		table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
+		table.ValueParam("$at", types.DatetimeValue(uint32(at))),
+	}
+
+	if err := db.Exec(ctx, TablePathPrefix("")+updateSQL, params...); err != nil {
+		return fmt.Errorf("failed to record auth timestamp for %s: %w", reviewerLogin, err)
+	}
+	return nil
+}
+
+// DeactivateStaleUsers marks INACTIVE the active users that keep failing authentication
+// since before failureThresholdBefore (unix seconds); returns the number deactivated
+func DeactivateStaleUsers(ctx context.Context, failureThresholdBefore int64) (int, error) {
+	return deactivateStaleUsers(ctx, NewDatabaseAdapter(), failureThresholdBefore)
+}
+
+// deactivateStaleUsers marks stale active users INACTIVE through db in one transaction
+func deactivateStaleUsers(ctx context.Context, db Database, failureThresholdBefore int64) (int, error) {
+	var deactivated int
+	err := db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
+		selectSQL := TablePathPrefix("") + `
+			SELECT reviewer_login, status, telegram_chat_id, created_at, last_auth_success_at, last_auth_failure_at
+			FROM users
+			WHERE status = "ACTIVE" AND last_auth_failure_at IS NOT NULL;
+		`
+
+		res, err := tx.Execute(ctx, selectSQL, table.NewQueryParameters())
+		if err != nil {
+			return fmt.Errorf("failed to query users with auth failures: %w", err)
+		}
+		defer res.Close()
+
+		if err := res.NextResultSetErr(ctx); err != nil {
+			return fmt.Errorf("failed to query users with auth failures: %w", err)
+		}
+
+		var users []*models.User
+		for res.NextRow() {
+			var user models.User
+			if err := yscan.ScanRow(&user, res); err != nil {
+				return fmt.Errorf("failed to scan user: %w", err)
+			}
+			users = append(users, &user)
+		}
+
+		logins := staleUserLogins(users, failureThresholdBefore)
+		deactivated = len(logins)
+		if deactivated == 0 {
+			return nil
+		}
+
+		updateSQL := TablePathPrefix("") + `
+			DECLARE $reviewer_logins AS List<Utf8>;
+
+			UPDATE users
+			SET status = "INACTIVE"
+			WHERE reviewer_login IN $reviewer_logins;
+		`
+
+		values := make([]types.Value, 0, len(logins))
+		for _, login := range logins {
+			values = append(values, types.TextValue(login))
+		}
+
+		_, err = tx.Execute(ctx, updateSQL, table.NewQueryParameters(
+			table.ValueParam("$reviewer_logins", types.ListValue(values...)),
+		))
+		if err != nil {
+			return fmt.Errorf("failed to deactivate stale users: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return deactivated, nil
+}
+
+// staleUserLogins returns logins of users with stale authentication
+func staleUserLogins(users []*models.User, cutoff int64) []string {
+	var logins []string
+	for _, user := range users {
+		if user.HasStaleAuth(cutoff) {
+			logins = append(logins, user.ReviewerLogin)
+		}
+	}
+	return logins
+}
+
+// ListReviewerLogins returns the logins of registered users, optionally limited to the given statuses
+func ListReviewerLogins(ctx context.Context, statuses ...string) ([]string, error) {
+	return listReviewerLogins(ctx, NewDatabaseAdapter(), statuses...)
+}
+
+// listReviewerLogins returns the logins of registered users through db
+func listReviewerLogins(ctx context.Context, db Database, statuses ...string) ([]string, error) {
 	sql := TablePathPrefix("") + `
-		SELECT reviewer_login, status, telegram_chat_id, created_at, last_auth_success_at, last_auth_failure_at
+		SELECT reviewer_login
 		FROM users
-		WHERE status = "ACTIVE";
+		ORDER BY reviewer_login;
 	`
+	var params []table.ParameterOption
 
-	res, err := Query(ctx, sql)
+	if len(statuses) > 0 {
+		sql = TablePathPrefix("") + `
+			DECLARE $statuses AS List<Utf8>;
+
+			SELECT reviewer_login
+			FROM users
+			WHERE status IN $statuses
+			ORDER BY reviewer_login;
+		`
+
+		values := make([]types.Value, 0, len(statuses))
+		for _, status := range statuses {
+			values = append(values, types.TextValue(status))
+		}
+		params = append(params, table.ValueParam("$statuses", types.ListValue(values...)))
+	}
+
+	res, err := db.Query(ctx, sql, params...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query active users: %w", err)
+		return nil, fmt.Errorf("failed to query reviewer logins: %w", err)
 	}
 	defer res.Close()
 
-	var users []*models.User
+	var logins []string
 	for res.NextRow() {
-		var user models.User
-		err = yscan.ScanRow(&user, res)
+		var login string
+		if err := res.ScanNamed(named.OptionalWithDefault("reviewer_login", &login)); err != nil {
+			return nil, fmt.Errorf("failed to scan reviewer login: %w", err)
+		}
+		logins = append(logins, login)
+	}
+
+	return logins, nil
+}
+
+// GetActiveUsers retrieves all active users
+func GetActiveUsers(ctx context.Context) ([]*models.User, error) {
+	return getActiveUsers(ctx, NewDatabaseAdapter())
+}
+
+// activeUsersPageSize is the page size used when listing active users
+const activeUsersPageSize = 500
+
+// getActiveUsers retrieves all active users through db, one page at a time
+func getActiveUsers(ctx context.Context, db Database) ([]*models.User, error) {
+	baseSQL := `
+		SELECT reviewer_login, status, telegram_chat_id, created_at, last_auth_success_at, last_auth_failure_at
+		FROM users
+		WHERE status = "ACTIVE"
+	`
+
+	var users []*models.User
+	cursor := ""
+	for {
+		page, next, err := queryPaged(ctx, db, baseSQL, "reviewer_login", scanUser, userKey, activeUsersPageSize, cursor)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+			return nil, fmt.Errorf("failed to query active users: %w", err)
 		}
-		users = append(users, &user)
+		users = append(users, page...)
+		if next == "" {
+			return users, nil
+		}
+		cursor = next
+	}
+}
+
+// scanUser scans the current row into a user
+func scanUser(res result.Result) (*models.User, error) {
+	var user models.User
+	if err := yscan.ScanRow(&user, res); err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
 	}
+	return &user, nil
+}
 
-	return users, nil
+// userKey returns the pagination key of a user
+func userKey(user *models.User) string {
+	return user.ReviewerLogin
 }
 
+// ErrUserSettingsNotFound is returned when a user has no settings row
+var ErrUserSettingsNotFound = errors.New("user settings not found")
+
 // GetUserSettings retrieves settings for a user
 func GetUserSettings(ctx context.Context, reviewerLogin string) (*models.UserSettings, error) {
+	return getUserSettings(ctx, NewDatabaseAdapter(), reviewerLogin)
+}
+
+// getUserSettings retrieves settings for a user through db
+func getUserSettings(ctx context.Context, db Database, reviewerLogin string) (*models.UserSettings, error) {
 	sql := TablePathPrefix("") + `
 		DECLARE $reviewer_login AS Utf8;
 
@@ -207,22 +522,134 @@ func GetUserSettings(ctx context.Context, reviewerLogin string) (*models.UserSet
 		table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
 	}
 
-	res, err := Query(ctx, sql, params...)
+	res, err := db.Query(ctx, sql, params...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user settings for %s: %w", reviewerLogin, err)
 	}
 	defer res.Close()
 
-	var settings models.UserSettings
 	if res.NextRow() {
-		err = yscan.ScanRow(&settings, res)
+		settings, err := scanUserSettings(res)
+		if err != nil {
+			return nil, err
+		}
+		if err := ensureSingleRow(res, "user_settings for "+reviewerLogin); err != nil {
+			return nil, err
+		}
+		return settings, nil
+	}
+
+	return nil, fmt.Errorf("%w for %s", ErrUserSettingsNotFound, reviewerLogin)
+}
+
+// scanUserSettings scans the current row into user settings
+func scanUserSettings(res result.Result) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	err := res.ScanNamed(
+		named.OptionalWithDefault("reviewer_login", &settings.ReviewerLogin),
+		named.OptionalWithDefault("response_deadline_shift_minutes", &settings.ResponseDeadlineShiftMinutes),
+		named.OptionalWithDefault("non_whitelist_cancel_delay_minutes", &settings.NonWhitelistCancelDelayMinutes),
+		named.OptionalWithDefault("notify_whitelist_timeout", &settings.NotifyWhitelistTimeout),
+		named.OptionalWithDefault("notify_non_whitelist_cancel", &settings.NotifyNonWhitelistCancel),
+		named.OptionalWithDefault("slot_shift_threshold_minutes", &settings.SlotShiftThresholdMinutes),
+		named.OptionalWithDefault("slot_shift_duration_minutes", &settings.SlotShiftDurationMinutes),
+		named.OptionalWithDefault("cleanup_durations_minutes", &settings.CleanupDurationsMinutes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user settings: %w", err)
+	}
+	return &settings, nil
+}
+
+// GetUserSettingsForUsers retrieves settings for several users with a single query
+// Logins without a row get DefaultUserSettings when withDefaults is set and are omitted otherwise
+func GetUserSettingsForUsers(ctx context.Context, logins []string, withDefaults bool) (map[string]*models.UserSettings, error) {
+	return getUserSettingsForUsers(ctx, NewDatabaseAdapter(), logins, withDefaults)
+}
+
+// getUserSettingsForUsers retrieves settings for several users through db
+func getUserSettingsForUsers(ctx context.Context, db Database, logins []string, withDefaults bool) (map[string]*models.UserSettings, error) {
+	unique := uniqueStrings(logins)
+	settingsByLogin := make(map[string]*models.UserSettings, len(unique))
+	if len(unique) == 0 {
+		return settingsByLogin, nil
+	}
+
+	sql := TablePathPrefix("") + `
+		DECLARE $reviewer_logins AS List<Utf8>;
+
+		SELECT reviewer_login, response_deadline_shift_minutes, non_whitelist_cancel_delay_minutes,
+		       notify_whitelist_timeout, notify_non_whitelist_cancel, slot_shift_threshold_minutes,
+		       slot_shift_duration_minutes, cleanup_durations_minutes
+		FROM user_settings
+		WHERE reviewer_login IN $reviewer_logins;
+	`
+
+	values := make([]types.Value, 0, len(unique))
+	for _, login := range unique {
+		values = append(values, types.TextValue(login))
+	}
+
+	params := []table.ParameterOption{
+		table.ValueParam("$reviewer_logins", types.ListValue(values...)),
+	}
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user settings for %d users: %w", len(unique), err)
+	}
+	defer res.Close()
+
+	for res.NextRow() {
+		settings, err := scanUserSettings(res)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan user settings: %w", err)
+			return nil, err
+		}
+		settingsByLogin[settings.ReviewerLogin] = settings
+	}
+
+	if withDefaults {
+		for _, login := range unique {
+			if _, ok := settingsByLogin[login]; !ok {
+				settingsByLogin[login] = models.DefaultUserSettings(login)
+			}
 		}
-		return &settings, nil
 	}
 
-	return nil, fmt.Errorf("user settings not found for %s", reviewerLogin)
+	return settingsByLogin, nil
+}
+
+// GetUserSettingsOrDefault retrieves settings for a user, creating the defaults if none exist
+func GetUserSettingsOrDefault(ctx context.Context, reviewerLogin string) (*models.UserSettings, error) {
+	return getUserSettingsOrDefault(ctx, NewDatabaseAdapter(), reviewerLogin)
+}
+
+// getUserSettingsOrDefault reads or creates a user's settings through db in one transaction
+func getUserSettingsOrDefault(ctx context.Context, db Database, reviewerLogin string) (*models.UserSettings, error) {
+	var settings *models.UserSettings
+
+	err := withTx(ctx, db, func(txCtx context.Context) error {
+		existing, err := getUserSettings(txCtx, db, reviewerLogin)
+		if err == nil {
+			settings = existing
+			return nil
+		}
+		if !errors.Is(err, ErrUserSettingsNotFound) {
+			return err
+		}
+
+		defaults := models.DefaultUserSettings(reviewerLogin)
+		if err := upsertUserSettings(txCtx, db, defaults); err != nil {
+			return fmt.Errorf("failed to create default user settings for %s: %w", reviewerLogin, err)
+		}
+		settings = defaults
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
 }
 
 // CreateDefaultUserSettings inserts default settings for a new user
@@ -231,8 +658,76 @@ func CreateDefaultUserSettings(ctx context.Context, reviewerLogin string) error
 	return UpsertUserSettings(ctx, settings)
 }
 
+// SettingsAuditHook receives the reviewer, setting column and its values before and after
+// a successful settings write; old is nil when the user had no settings row
+type SettingsAuditHook func(login, field string, old, new any)
+
+// settingsAuditHook is notified after settings writes (nil means no-op)
+var settingsAuditHook atomic.Pointer[SettingsAuditHook]
+
+// SetSettingsAuditHook installs a hook for auditing settings changes. Passing nil removes it.
+func SetSettingsAuditHook(hook SettingsAuditHook) {
+	if hook == nil {
+		settingsAuditHook.Store(nil)
+		return
+	}
+	settingsAuditHook.Store(&hook)
+}
+
+// settingField is a user_settings column name with its value
+type settingField struct {
+	name  string
+	value any
+}
+
+// settingsFields lists the user_settings columns of settings in table order
+func settingsFields(settings *models.UserSettings) []settingField {
+	return []settingField{
+		{"response_deadline_shift_minutes", settings.ResponseDeadlineShiftMinutes},
+		{"non_whitelist_cancel_delay_minutes", settings.NonWhitelistCancelDelayMinutes},
+		{"notify_whitelist_timeout", settings.NotifyWhitelistTimeout},
+		{"notify_non_whitelist_cancel", settings.NotifyNonWhitelistCancel},
+		{"slot_shift_threshold_minutes", settings.SlotShiftThresholdMinutes},
+		{"slot_shift_duration_minutes", settings.SlotShiftDurationMinutes},
+		{"cleanup_durations_minutes", settings.CleanupDurationsMinutes},
+	}
+}
+
+// settingValue returns the value of a user_settings column, or nil if settings is nil
+func settingValue(settings *models.UserSettings, field string) any {
+	if settings == nil {
+		return nil
+	}
+	for _, f := range settingsFields(settings) {
+		if f.name == field {
+			return f.value
+		}
+	}
+	return nil
+}
+
+// priorSettingsForAudit loads the current settings when an audit hook is installed
+func priorSettingsForAudit(ctx context.Context, db Database, reviewerLogin string) (*SettingsAuditHook, *models.UserSettings) {
+	hook := settingsAuditHook.Load()
+	if hook == nil {
+		return nil, nil
+	}
+	prior, err := getUserSettings(ctx, db, reviewerLogin)
+	if err != nil && !errors.Is(err, ErrUserSettingsNotFound) {
+		log.Printf("[YDB] settings audit: failed to load prior settings for %s: %v", reviewerLogin, err)
+	}
+	return hook, prior
+}
+
 // UpsertUserSettings inserts or updates user settings
 func UpsertUserSettings(ctx context.Context, settings *models.UserSettings) error {
+	return upsertUserSettings(ctx, NewDatabaseAdapter(), settings)
+}
+
+// upsertUserSettings inserts or updates user settings through db
+func upsertUserSettings(ctx context.Context, db Database, settings *models.UserSettings) error {
+	hook, prior := priorSettingsForAudit(ctx, db, settings.ReviewerLogin)
+
 	sql := TablePathPrefix("") + `
 		DECLARE $reviewer_login AS Utf8;
 		DECLARE $response_deadline_shift_minutes AS Int32;
@@ -265,11 +760,30 @@ func UpsertUserSettings(ctx context.Context, settings *models.UserSettings) erro
 		table.ValueParam("$cleanup_durations_minutes", types.Int32Value(settings.CleanupDurationsMinutes)),
 	}
 
-	return Exec(ctx, sql, params...)
+	if err := db.Exec(ctx, sql, params...); err != nil {
+		return err
+	}
+
+	if hook != nil {
+		for _, f := range settingsFields(settings) {
+			old := settingValue(prior, f.name)
+			if old != f.value {
+				(*hook)(settings.ReviewerLogin, f.name, old, f.value)
+			}
+		}
+	}
+	return nil
 }
 
 // UpdateUserSetting updates a single user setting field
 func UpdateUserSetting(ctx context.Context, reviewerLogin, field string, value any) error {
+	return updateUserSetting(ctx, NewDatabaseAdapter(), reviewerLogin, field, value)
+}
+
+// updateUserSetting updates a single user setting field through db
+func updateUserSetting(ctx context.Context, db Database, reviewerLogin, field string, value any) error {
+	hook, prior := priorSettingsForAudit(ctx, db, reviewerLogin)
+
 	sql := fmt.Sprintf(TablePathPrefix("")+`
 		DECLARE $reviewer_login AS Utf8;
 		DECLARE $value AS %s;
@@ -296,7 +810,50 @@ func UpdateUserSetting(ctx context.Context, reviewerLogin, field string, value a
 		paramValue,
 	}
 
-	return Exec(ctx, sql, params...)
+	if err := db.Exec(ctx, sql, params...); err != nil {
+		return err
+	}
+
+	if hook != nil {
+		(*hook)(reviewerLogin, field, settingValue(prior, field), value)
+	}
+	return nil
+}
+
+// SetResponseDeadlineShift sets response_deadline_shift_minutes after validating its range
+func SetResponseDeadlineShift(ctx context.Context, reviewerLogin string, minutes int32) error {
+	return setMinutesSetting(ctx, NewDatabaseAdapter(), reviewerLogin, "response_deadline_shift_minutes", minutes)
+}
+
+// SetNonWhitelistCancelDelay sets non_whitelist_cancel_delay_minutes after validating its range
+func SetNonWhitelistCancelDelay(ctx context.Context, reviewerLogin string, minutes int32) error {
+	return setMinutesSetting(ctx, NewDatabaseAdapter(), reviewerLogin, "non_whitelist_cancel_delay_minutes", minutes)
+}
+
+// SetSlotShiftThreshold sets slot_shift_threshold_minutes after validating its range
+func SetSlotShiftThreshold(ctx context.Context, reviewerLogin string, minutes int32) error {
+	return setMinutesSetting(ctx, NewDatabaseAdapter(), reviewerLogin, "slot_shift_threshold_minutes", minutes)
+}
+
+// SetSlotShiftDuration sets slot_shift_duration_minutes after validating its range
+func SetSlotShiftDuration(ctx context.Context, reviewerLogin string, minutes int32) error {
+	return setMinutesSetting(ctx, NewDatabaseAdapter(), reviewerLogin, "slot_shift_duration_minutes", minutes)
+}
+
+// SetCleanupDuration sets cleanup_durations_minutes after validating its range
+func SetCleanupDuration(ctx context.Context, reviewerLogin string, minutes int32) error {
+	return setMinutesSetting(ctx, NewDatabaseAdapter(), reviewerLogin, "cleanup_durations_minutes", minutes)
+}
+
+// setMinutesSetting validates a minute-based setting and writes it through db
+func setMinutesSetting(ctx context.Context, db Database, reviewerLogin, field string, minutes int32) error {
+	if reviewerLogin == "" {
+		return fmt.Errorf("reviewer login is required")
+	}
+	if minutes < models.MinSettingMinutes || minutes > models.MaxSettingMinutes {
+		return fmt.Errorf("%s must be between %d and %d minutes, got %d", field, models.MinSettingMinutes, models.MaxSettingMinutes, minutes)
+	}
+	return updateUserSetting(ctx, db, reviewerLogin, field, minutes)
 }
 
 func getFieldTypeForValue(value any) string {
@@ -312,6 +869,11 @@ func getFieldTypeForValue(value any) string {
 
 // GetUserWhitelist retrieves all whitelist entries for a user
 func GetUserWhitelist(ctx context.Context, reviewerLogin string) ([]*models.WhitelistEntry, error) {
+	return getUserWhitelist(ctx, NewDatabaseAdapter(), reviewerLogin)
+}
+
+// getUserWhitelist retrieves a user's whitelist entries through db
+func getUserWhitelist(ctx context.Context, db Database, reviewerLogin string) ([]*models.WhitelistEntry, error) {
 	sql := TablePathPrefix("") + `
 		DECLARE $reviewer_login AS Utf8;
 
@@ -324,7 +886,7 @@ func GetUserWhitelist(ctx context.Context, reviewerLogin string) ([]*models.Whit
 		table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
 	}
 
-	res, err := Query(ctx, sql, params...)
+	res, err := db.Query(ctx, sql, params...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query whitelist for %s: %w", reviewerLogin, err)
 	}
@@ -343,87 +905,322 @@ func GetUserWhitelist(ctx context.Context, reviewerLogin string) ([]*models.Whit
 	return entries, nil
 }
 
-// AddToWhitelist adds an entry to a user's whitelist
-func AddToWhitelist(ctx context.Context, entry *models.WhitelistEntry) error {
-	sql := TablePathPrefix("") + `
+// GetUserWhitelistGrouped returns a user's whitelist split into family and project names
+func GetUserWhitelistGrouped(ctx context.Context, reviewerLogin string) (families []string, projects []string, err error) {
+	entries, err := GetUserWhitelist(ctx, reviewerLogin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	families, projects = groupWhitelistEntries(entries)
+	return families, projects, nil
+}
+
+// groupWhitelistEntries splits entries by type in one pass, keeping their order
+// Entries of unknown type are skipped
+func groupWhitelistEntries(entries []*models.WhitelistEntry) (families []string, projects []string) {
+	for _, entry := range entries {
+		switch entry.EntryType {
+		case models.EntryTypeFamily:
+			families = append(families, entry.Name)
+		case models.EntryTypeProject:
+			projects = append(projects, entry.Name)
+		}
+	}
+	return families, projects
+}
+
+// upsertWhitelistEntrySQL writes a whitelist row keyed by (reviewer_login, entry_type, name)
+// UPSERT makes re-adding an existing entry a no-op instead of a constraint error
+const upsertWhitelistEntrySQL = `
 		DECLARE $reviewer_login AS Utf8;
 		DECLARE $entry_type AS Utf8;
 		DECLARE $name AS Utf8;
 
-		INSERT INTO user_project_whitelist (reviewer_login, entry_type, name)
+		UPSERT INTO user_project_whitelist (reviewer_login, entry_type, name)
 		VALUES ($reviewer_login, $entry_type, $name);
 	`
 
-	params := []table.ParameterOption{
-		table.ValueParam("$reviewer_login", types.TextValue(entry.ReviewerLogin)),
-		table.ValueParam("$entry_type", types.TextValue(entry.EntryType)),
-		table.ValueParam("$name", types.TextValue(entry.Name)),
+// AddToWhitelist adds an entry to a user's whitelist
+// Adding an entry that already exists succeeds without creating a duplicate
+func AddToWhitelist(ctx context.Context, entry *models.WhitelistEntry) error {
+	return addToWhitelist(ctx, NewDatabaseAdapter(), entry)
+}
+
+// addToWhitelist adds an entry to a user's whitelist through db
+// The existing entries are checked in the same transaction as the write, because the
+// table key is the stored name and names differing only in case would not collide
+func addToWhitelist(ctx context.Context, db Database, entry *models.WhitelistEntry) error {
+	entry.Normalize()
+	if err := entry.Validate(); err != nil {
+		return fmt.Errorf("invalid whitelist entry: %w", err)
 	}
 
-	return Exec(ctx, sql, params...)
+	return withTx(ctx, db, func(txCtx context.Context) error {
+		existing, err := getUserWhitelist(txCtx, db, entry.ReviewerLogin)
+		if err != nil {
+			return err
+		}
+		if findWhitelistEntry(existing, entry) != nil {
+			return nil
+		}
+
+		sql := TablePathPrefix("") + upsertWhitelistEntrySQL
+
+		params := []table.ParameterOption{
+			table.ValueParam("$reviewer_login", types.TextValue(entry.ReviewerLogin)),
+			table.ValueParam("$entry_type", types.TextValue(entry.EntryType)),
+			table.ValueParam("$name", types.TextValue(entry.Name)),
+		}
+
+		return db.Exec(txCtx, sql, params...)
+	})
+}
+
+// ReplaceUserWhitelist atomically replaces all whitelist entries of a user with the given set
+func ReplaceUserWhitelist(ctx context.Context, reviewerLogin string, entries []*models.WhitelistEntry) error {
+	return replaceUserWhitelist(ctx, NewDatabaseAdapter(), reviewerLogin, entries)
+}
+
+// replaceUserWhitelist deletes a user's whitelist and writes the new entries through db in one transaction
+func replaceUserWhitelist(ctx context.Context, db Database, reviewerLogin string, entries []*models.WhitelistEntry) error {
+	normalized, err := validateWhitelistEntries(reviewerLogin, entries)
+	if err != nil {
+		return err
+	}
+	entries = dedupeWhitelistEntries(normalized)
+
+	return db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
+		deleteSQL := TablePathPrefix("") + `
+			DECLARE $reviewer_login AS Utf8;
+
+			DELETE FROM user_project_whitelist
+			WHERE reviewer_login = $reviewer_login;
+		`
+
+		_, err := tx.Execute(ctx, deleteSQL, table.NewQueryParameters(
+			table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
+		))
+		if err != nil {
+			return fmt.Errorf("failed to clear whitelist for %s: %w", reviewerLogin, err)
+		}
+
+		for _, entry := range entries {
+			_, err = tx.Execute(ctx, TablePathPrefix("")+upsertWhitelistEntrySQL, table.NewQueryParameters(
+				table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
+				table.ValueParam("$entry_type", types.TextValue(entry.EntryType)),
+				table.ValueParam("$name", types.TextValue(entry.Name)),
+			))
+			if err != nil {
+				return fmt.Errorf("failed to insert whitelist entry %s: %w", entry.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// validateWhitelistEntries checks every entry before a bulk whitelist write and returns
+// normalized copies; the caller's entries are left untouched
+func validateWhitelistEntries(reviewerLogin string, entries []*models.WhitelistEntry) ([]*models.WhitelistEntry, error) {
+	if reviewerLogin == "" {
+		return nil, fmt.Errorf("reviewer login is required")
+	}
+
+	normalized := make([]*models.WhitelistEntry, 0, len(entries))
+	for i, entry := range entries {
+		if entry == nil {
+			return nil, fmt.Errorf("whitelist entry %d is nil", i)
+		}
+		if entry.ReviewerLogin != "" && entry.ReviewerLogin != reviewerLogin {
+			return nil, fmt.Errorf("whitelist entry %d belongs to %s, not %s", i, entry.ReviewerLogin, reviewerLogin)
+		}
+		copied := *entry
+		copied.Normalize()
+		if err := copied.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid whitelist entry %d: %w", i, err)
+		}
+		normalized = append(normalized, &copied)
+	}
+
+	return normalized, nil
+}
+
+// dedupeWhitelistEntries drops entries that refer to an item already in the list
+func dedupeWhitelistEntries(entries []*models.WhitelistEntry) []*models.WhitelistEntry {
+	var unique []*models.WhitelistEntry
+	for _, entry := range entries {
+		if findWhitelistEntry(unique, entry) == nil {
+			unique = append(unique, entry)
+		}
+	}
+	return unique
+}
+
+// findWhitelistEntry returns the entry that refers to the same item as target, or nil
+func findWhitelistEntry(entries []*models.WhitelistEntry, target *models.WhitelistEntry) *models.WhitelistEntry {
+	for _, entry := range entries {
+		if entry.SameEntry(target) {
+			return entry
+		}
+	}
+	return nil
 }
 
 // RemoveFromWhitelist removes an entry from a user's whitelist
+// The name is matched ignoring case and spacing; removing a missing entry is a no-op
 func RemoveFromWhitelist(ctx context.Context, reviewerLogin, name string) error {
-	sql := TablePathPrefix("") + `
-		DECLARE $reviewer_login AS Utf8;
-		DECLARE $name AS Utf8;
+	return removeFromWhitelist(ctx, NewDatabaseAdapter(), reviewerLogin, name)
+}
 
-		DELETE FROM user_project_whitelist
-		WHERE reviewer_login = $reviewer_login AND name = $name;
-	`
+// removeFromWhitelist resolves the stored entries matching name and deletes them by
+// their exact stored names through db in one transaction
+func removeFromWhitelist(ctx context.Context, db Database, reviewerLogin, name string) error {
+	return withTx(ctx, db, func(txCtx context.Context) error {
+		entries, err := getUserWhitelist(txCtx, db, reviewerLogin)
+		if err != nil {
+			return err
+		}
 
-	params := []table.ParameterOption{
-		table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
-		table.ValueParam("$name", types.TextValue(name)),
-	}
+		var storedNames []string
+		for _, entry := range entries {
+			if models.WhitelistNamesEqual(entry.EntryType, entry.Name, name) && !slices.Contains(storedNames, entry.Name) {
+				storedNames = append(storedNames, entry.Name)
+			}
+		}
+		sql := TablePathPrefix("") + `
+			DECLARE $reviewer_login AS Utf8;
+			DECLARE $name AS Utf8;
 
-	return Exec(ctx, sql, params...)
+			DELETE FROM user_project_whitelist
+			WHERE reviewer_login = $reviewer_login AND name = $name;
+		`
+
+		for _, storedName := range storedNames {
+			params := []table.ParameterOption{
+				table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
+				table.ValueParam("$name", types.TextValue(storedName)),
+			}
+			if err := db.Exec(txCtx, sql, params...); err != nil {
+				return fmt.Errorf("failed to remove whitelist entry %s: %w", storedName, err)
+			}
+		}
+		return nil
+	})
 }
 
 // IsInWhitelist checks if a project or family is in a user's whitelist
+// Names are compared ignoring case and spacing differences
 func IsInWhitelist(ctx context.Context, reviewerLogin, projectName, familyLabel string) (bool, error) {
+	entries, err := GetUserWhitelist(ctx, reviewerLogin)
+	if err != nil {
+		return false, fmt.Errorf("failed to check whitelist: %w", err)
+	}
+
+	return whitelistMatches(entries, projectName, familyLabel), nil
+}
+
+// MatchWhitelist checks the whitelist and reports which entry type matched
+// A PROJECT match is preferred over a FAMILY match when both apply
+func MatchWhitelist(ctx context.Context, reviewerLogin, projectName, familyLabel string) (bool, string, error) {
+	entries, err := GetUserWhitelist(ctx, reviewerLogin)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check whitelist: %w", err)
+	}
+
+	entryType, matched := matchWhitelistEntryType(entries, projectName, familyLabel)
+	return matched, entryType, nil
+}
+
+// IsProjectWhitelisted checks if a project is whitelisted directly or through its family
+// A project with no known family can still match a PROJECT entry
+func IsProjectWhitelisted(ctx context.Context, reviewerLogin, projectName string) (bool, error) {
+	familyLabel, _, err := lookupFamilyLabel(ctx, projectName)
+	if err != nil {
+		return false, err
+	}
+
+	entries, err := GetUserWhitelist(ctx, reviewerLogin)
+	if err != nil {
+		return false, err
+	}
+
+	return whitelistMatches(entries, projectName, familyLabel), nil
+}
+
+// whitelistMatches reports whether any entry covers the project or its family
+func whitelistMatches(entries []*models.WhitelistEntry, projectName, familyLabel string) bool {
+	_, matched := matchWhitelistEntryType(entries, projectName, familyLabel)
+	return matched
+}
+
+// matchWhitelistEntryType returns the type of the entry covering the project or its family,
+// preferring PROJECT over FAMILY
+func matchWhitelistEntryType(entries []*models.WhitelistEntry, projectName, familyLabel string) (string, bool) {
+	familyMatched := false
+	for _, entry := range entries {
+		switch entry.EntryType {
+		case models.EntryTypeProject:
+			if projectName != "" && models.WhitelistNamesEqual(entry.EntryType, entry.Name, projectName) {
+				return models.EntryTypeProject, true
+			}
+		case models.EntryTypeFamily:
+			if familyLabel != "" && models.WhitelistNamesEqual(entry.EntryType, entry.Name, familyLabel) {
+				familyMatched = true
+			}
+		}
+	}
+	if familyMatched {
+		return models.EntryTypeFamily, true
+	}
+	return "", false
+}
+
+// GetWhitelistEntryCount returns the number of whitelist entries for a user
+func GetWhitelistEntryCount(ctx context.Context, reviewerLogin string) (uint64, error) {
 	sql := TablePathPrefix("") + `
 		DECLARE $reviewer_login AS Utf8;
-		DECLARE $project_name AS Utf8;
-		DECLARE $family_label AS Utf8;
 
 		SELECT COUNT(*) AS count
 		FROM user_project_whitelist
-		WHERE reviewer_login = $reviewer_login
-		  AND (
-		    (entry_type = "PROJECT" AND name = $project_name)
-		    OR
-		    (entry_type = "FAMILY" AND name = $family_label)
-		  );
+		WHERE reviewer_login = $reviewer_login;
 	`
 
 	params := []table.ParameterOption{
 		table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
-		table.ValueParam("$project_name", types.TextValue(projectName)),
-		table.ValueParam("$family_label", types.TextValue(familyLabel)),
 	}
 
 	res, err := Query(ctx, sql, params...)
 	if err != nil {
-		return false, fmt.Errorf("failed to check whitelist: %w", err)
+		return 0, fmt.Errorf("failed to count whitelist entries for %s: %w", reviewerLogin, err)
 	}
 	defer res.Close()
 
+	var count uint64
 	if res.NextRow() {
-		var count uint64
 		err = yscan.ScanRow(&count, res)
 		if err != nil {
-			return false, fmt.Errorf("failed to scan count: %w", err)
+			return 0, fmt.Errorf("failed to scan count: %w", err)
 		}
-		return count > 0, nil
 	}
 
-	return false, nil
+	return count, nil
 }
 
 // GetFamilyLabelForProject looks up a project's family label
 func GetFamilyLabelForProject(ctx context.Context, projectName string) (string, error) {
+	familyLabel, found, err := lookupFamilyLabel(ctx, projectName)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("project %s not found in project_families", projectName)
+	}
+	return familyLabel, nil
+}
+
+// lookupFamilyLabel looks up a project's family label, reporting whether it was found
+func lookupFamilyLabel(ctx context.Context, projectName string) (string, bool, error) {
 	sql := TablePathPrefix("") + `
 		DECLARE $project_name AS Utf8;
 
@@ -438,7 +1235,7 @@ func GetFamilyLabelForProject(ctx context.Context, projectName string) (string,
 
 	res, err := Query(ctx, sql, params...)
 	if err != nil {
-		return "", fmt.Errorf("failed to query project family: %w", err)
+		return "", false, fmt.Errorf("failed to query project family: %w", err)
 	}
 	defer res.Close()
 
@@ -446,12 +1243,90 @@ func GetFamilyLabelForProject(ctx context.Context, projectName string) (string,
 		var familyLabel string
 		err = yscan.ScanRow(&familyLabel, res)
 		if err != nil {
-			return "", fmt.Errorf("failed to scan family label: %w", err)
+			return "", false, fmt.Errorf("failed to scan family label: %w", err)
+		}
+		return familyLabel, true, nil
+	}
+
+	return "", false, nil
+}
+
+// GetFamilyLabelsForProjects resolves family labels for several projects with a single query
+// Projects without a known family are absent from the returned map
+func GetFamilyLabelsForProjects(ctx context.Context, projectNames []string) (map[string]string, error) {
+	return getFamilyLabelsForProjects(ctx, NewDatabaseAdapter(), projectNames)
+}
+
+// getFamilyLabelsForProjects resolves family labels for several projects through db
+func getFamilyLabelsForProjects(ctx context.Context, db Database, projectNames []string) (map[string]string, error) {
+	names := uniqueStrings(projectNames)
+	if len(names) == 0 {
+		return map[string]string{}, nil
+	}
+
+	sql := TablePathPrefix("") + `
+		DECLARE $project_names AS List<Utf8>;
+
+		SELECT family_label, project_name
+		FROM project_families
+		WHERE project_name IN $project_names;
+	`
+
+	values := make([]types.Value, 0, len(names))
+	for _, name := range names {
+		values = append(values, types.TextValue(name))
+	}
+
+	params := []table.ParameterOption{
+		table.ValueParam("$project_names", types.ListValue(values...)),
+	}
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query project families: %w", err)
+	}
+	defer res.Close()
+
+	var families []*models.ProjectFamily
+	for res.NextRow() {
+		var family models.ProjectFamily
+		err = yscan.ScanRow(&family, res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project family: %w", err)
 		}
-		return familyLabel, nil
+		families = append(families, &family)
 	}
 
-	return "", fmt.Errorf("project %s not found in project_families", projectName)
+	return familyLabelsByProject(families, names), nil
+}
+
+// uniqueStrings returns non-empty values without duplicates, preserving order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// familyLabelsByProject maps requested project names to their family labels
+func familyLabelsByProject(families []*models.ProjectFamily, requested []string) map[string]string {
+	wanted := make(map[string]bool, len(requested))
+	for _, name := range requested {
+		wanted[name] = true
+	}
+
+	labels := make(map[string]string, len(families))
+	for _, family := range families {
+		if family != nil && wanted[family.ProjectName] {
+			labels[family.ProjectName] = family.FamilyLabel
+		}
+	}
+	return labels
 }
 
 // GetAllProjectFamilies retrieves all project families
@@ -515,32 +1390,44 @@ func GetProjectsByFamily(ctx context.Context, familyLabel string) ([]string, err
 
 // UpsertProjectFamilies replaces all project families
 func UpsertProjectFamilies(ctx context.Context, families []*models.ProjectFamily) error {
-	return DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
+	return upsertProjectFamilies(ctx, NewDatabaseAdapter(), families)
+}
+
+// upsertProjectFamilies replaces all project families through db, writing them with a
+// single bulk UPSERT instead of one statement per family
+func upsertProjectFamilies(ctx context.Context, db Database, families []*models.ProjectFamily) error {
+	return db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
 		// First, delete all existing entries
 		_, err := tx.Execute(ctx, TablePathPrefix("")+`DELETE FROM project_families;`, table.NewQueryParameters())
 		if err != nil {
 			return fmt.Errorf("failed to clear project_families: %w", err)
 		}
 
-		// Then insert all families
-		for _, family := range families {
-			sql := TablePathPrefix("") + `
-				DECLARE $family_label AS Utf8;
-				DECLARE $project_name AS Utf8;
+		if len(families) == 0 {
+			return nil
+		}
 
-				INSERT INTO project_families (family_label, project_name)
-				VALUES ($family_label, $project_name);
-			`
+		// Then write all families in one statement
+		sql := TablePathPrefix("") + `
+			DECLARE $families AS List<Struct<family_label: Utf8, project_name: Utf8>>;
 
-			params := []table.ParameterOption{
-				table.ValueParam("$family_label", types.TextValue(family.FamilyLabel)),
-				table.ValueParam("$project_name", types.TextValue(family.ProjectName)),
-			}
+			UPSERT INTO project_families (family_label, project_name)
+			SELECT family_label, project_name FROM AS_TABLE($families);
+		`
 
-			_, err = tx.Execute(ctx, sql, table.NewQueryParameters(params...))
-			if err != nil {
-				return fmt.Errorf("failed to insert project family: %w", err)
-			}
+		rows := make([]types.Value, 0, len(families))
+		for _, family := range families {
+			rows = append(rows, types.StructValue(
+				types.StructFieldValue("family_label", types.TextValue(family.FamilyLabel)),
+				types.StructFieldValue("project_name", types.TextValue(family.ProjectName)),
+			))
+		}
+
+		_, err = tx.Execute(ctx, sql, table.NewQueryParameters(
+			table.ValueParam("$families", types.ListValue(rows...)),
+		))
+		if err != nil {
+			return fmt.Errorf("failed to upsert project families: %w", err)
 		}
 
 		return nil
@@ -549,6 +1436,18 @@ func UpsertProjectFamilies(ctx context.Context, families []*models.ProjectFamily
 
 // CreateReviewRequest creates a new review request
 func CreateReviewRequest(ctx context.Context, req *models.ReviewRequest) error {
+	return createReviewRequest(ctx, NewDatabaseAdapter(), req)
+}
+
+// createReviewRequest creates a new review request through db
+func createReviewRequest(ctx context.Context, db Database, req *models.ReviewRequest) error {
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("invalid review request: %w", err)
+	}
+	if !models.IsValidReviewID(req.ID) {
+		return fmt.Errorf("invalid review request: %s: %q is not a UUID", models.ErrInvalidReviewID, req.ID)
+	}
+
 	sql := TablePathPrefix("") + `
 		DECLARE $id AS Utf8;
 		DECLARE $reviewer_login AS Utf8;
@@ -576,11 +1475,91 @@ func CreateReviewRequest(ctx context.Context, req *models.ReviewRequest) error {
 		table.ValueParam("$family_label", optionalText(req.FamilyLabel)),
 	}
 
-	return Exec(ctx, sql, params...)
+	return db.Exec(ctx, sql, params...)
+}
+
+// GetReviewRequestHistoryBySlot retrieves every review request for a calendar slot, newest first
+func GetReviewRequestHistoryBySlot(ctx context.Context, calendarSlotID string) ([]*models.ReviewRequest, error) {
+	return getReviewRequestHistoryBySlot(ctx, NewDatabaseAdapter(), calendarSlotID)
+}
+
+// getReviewRequestHistoryBySlot retrieves every review request for a calendar slot through db
+func getReviewRequestHistoryBySlot(ctx context.Context, db Database, calendarSlotID string) ([]*models.ReviewRequest, error) {
+	sql := TablePathPrefix("") + `
+		DECLARE $calendar_slot_id AS Utf8;
+
+		SELECT id, reviewer_login, notification_id, project_name, family_label, review_start_time,
+		       calendar_slot_id, booking_id, decision_deadline, non_whitelist_cancel_at, telegram_message_id,
+		       status, created_at, decided_at
+		FROM review_requests
+		WHERE calendar_slot_id = $calendar_slot_id
+		ORDER BY created_at DESC, id DESC;
+	`
+
+	params := []table.ParameterOption{
+		table.ValueParam("$calendar_slot_id", types.TextValue(calendarSlotID)),
+	}
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review request history for slot %s: %w", calendarSlotID, err)
+	}
+	defer res.Close()
+
+	var requests []*models.ReviewRequest
+	for res.NextRow() {
+		req, err := scanReviewRequest(res)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+// CreateReviewRequestIfAbsent creates req unless a review request already exists for its calendar slot
+// It returns the stored request and whether it was created; the check and insert share one transaction
+func CreateReviewRequestIfAbsent(ctx context.Context, req *models.ReviewRequest) (*models.ReviewRequest, bool, error) {
+	return createReviewRequestIfAbsent(ctx, NewDatabaseAdapter(), req)
+}
+
+// createReviewRequestIfAbsent creates req through db unless its calendar slot already has a review request
+func createReviewRequestIfAbsent(ctx context.Context, db Database, req *models.ReviewRequest) (*models.ReviewRequest, bool, error) {
+	var stored *models.ReviewRequest
+	created := false
+
+	err := withTx(ctx, db, func(txCtx context.Context) error {
+		existing, err := getReviewRequestByCalendarSlotID(txCtx, db, req.CalendarSlotID)
+		if err == nil {
+			stored = existing
+			return nil
+		}
+		if !errors.Is(err, ErrReviewRequestNotFound) {
+			return err
+		}
+
+		if err := createReviewRequest(txCtx, db, req); err != nil {
+			return err
+		}
+		stored = req
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create review request for slot %s: %w", req.CalendarSlotID, err)
+	}
+
+	return stored, created, nil
 }
 
 // GetReviewRequestByID retrieves a review request by ID
 func GetReviewRequestByID(ctx context.Context, id string) (*models.ReviewRequest, error) {
+	return getReviewRequestByID(ctx, NewDatabaseAdapter(), id)
+}
+
+// getReviewRequestByID retrieves a review request by ID through db
+func getReviewRequestByID(ctx context.Context, db Database, id string) (*models.ReviewRequest, error) {
 	sql := TablePathPrefix("") + `
 		DECLARE $id AS Utf8;
 
@@ -595,21 +1574,37 @@ func GetReviewRequestByID(ctx context.Context, id string) (*models.ReviewRequest
 		table.ValueParam("$id", types.TextValue(id)),
 	}
 
-	res, err := Query(ctx, sql, params...)
+	res, err := db.Query(ctx, sql, params...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query review request: %w", err)
 	}
 	defer res.Close()
 
 	if res.NextRow() {
-		return scanReviewRequest(res)
+		req, err := scanReviewRequest(res)
+		if err != nil {
+			return nil, err
+		}
+		if err := ensureSingleRow(res, "review_requests with id "+id); err != nil {
+			return nil, err
+		}
+		return req, nil
 	}
 
 	return nil, fmt.Errorf("review request not found: %s", id)
 }
 
-// GetReviewRequestByCalendarSlotID retrieves a review request by calendar slot ID
+// ErrReviewRequestNotFound is returned when no review request matches a lookup
+var ErrReviewRequestNotFound = errors.New("review request not found")
+
+// GetReviewRequestByCalendarSlotID retrieves the most recent review request for a calendar slot
+// A reopened slot can have several requests; use GetReviewRequestHistoryBySlot to get all of them
 func GetReviewRequestByCalendarSlotID(ctx context.Context, calendarSlotID string) (*models.ReviewRequest, error) {
+	return getReviewRequestByCalendarSlotID(ctx, NewDatabaseAdapter(), calendarSlotID)
+}
+
+// getReviewRequestByCalendarSlotID retrieves a review request by calendar slot ID through db
+func getReviewRequestByCalendarSlotID(ctx context.Context, db Database, calendarSlotID string) (*models.ReviewRequest, error) {
 	sql := TablePathPrefix("") + `
 		DECLARE $calendar_slot_id AS Utf8;
 
@@ -617,14 +1612,16 @@ func GetReviewRequestByCalendarSlotID(ctx context.Context, calendarSlotID string
 		       calendar_slot_id, booking_id, decision_deadline, non_whitelist_cancel_at, telegram_message_id,
 		       status, created_at, decided_at
 		FROM review_requests
-		WHERE calendar_slot_id = $calendar_slot_id;
+		WHERE calendar_slot_id = $calendar_slot_id
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1;
 	`
 
 	params := []table.ParameterOption{
 		table.ValueParam("$calendar_slot_id", types.TextValue(calendarSlotID)),
 	}
 
-	res, err := Query(ctx, sql, params...)
+	res, err := db.Query(ctx, sql, params...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query review request by slot ID: %w", err)
 	}
@@ -634,7 +1631,7 @@ func GetReviewRequestByCalendarSlotID(ctx context.Context, calendarSlotID string
 		return scanReviewRequest(res)
 	}
 
-	return nil, fmt.Errorf("review request not found with calendar_slot_id: %s", calendarSlotID)
+	return nil, fmt.Errorf("%w with calendar_slot_id: %s", ErrReviewRequestNotFound, calendarSlotID)
 }
 
 // GetReviewRequestsByStatus retrieves review requests by status
@@ -678,6 +1675,55 @@ func GetReviewRequestsByStatus(ctx context.Context, statuses []string) ([]*model
 	return requests, nil
 }
 
+// GetReviewRequestsNeedingProjectResolution retrieves UNKNOWN_PROJECT_REVIEW requests oldest first
+// A positive limit caps the batch size; zero or negative returns all of them
+func GetReviewRequestsNeedingProjectResolution(ctx context.Context, limit int) ([]*models.ReviewRequest, error) {
+	return getReviewRequestsNeedingProjectResolution(ctx, NewDatabaseAdapter(), limit)
+}
+
+// getReviewRequestsNeedingProjectResolution retrieves requests awaiting project resolution through db
+func getReviewRequestsNeedingProjectResolution(ctx context.Context, db Database, limit int) ([]*models.ReviewRequest, error) {
+	declareLimit, limitClause := "", ""
+	params := []table.ParameterOption{
+		table.ValueParam("$status", types.TextValue(models.StatusUnknownProjectReview)),
+	}
+	if limit > 0 {
+		declareLimit = "DECLARE $limit AS Uint64;"
+		limitClause = "LIMIT $limit"
+		params = append(params, table.ValueParam("$limit", types.Uint64Value(uint64(limit))))
+	}
+
+	sql := TablePathPrefix("") + fmt.Sprintf(`
+		DECLARE $status AS Utf8;
+		%s
+
+		SELECT id, reviewer_login, notification_id, project_name, family_label, review_start_time,
+		       calendar_slot_id, booking_id, decision_deadline, non_whitelist_cancel_at, telegram_message_id,
+		       status, created_at, decided_at
+		FROM review_requests
+		WHERE status = $status
+		ORDER BY created_at, id
+		%s;
+	`, declareLimit, limitClause)
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review requests needing project resolution: %w", err)
+	}
+	defer res.Close()
+
+	var requests []*models.ReviewRequest
+	for res.NextRow() {
+		req, err := scanReviewRequest(res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review request: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
 // GetReviewRequestsByUserAndStatus retrieves review requests for a user with specific statuses
 func GetReviewRequestsByUserAndStatus(ctx context.Context, reviewerLogin string, statuses []string) ([]*models.ReviewRequest, error) {
 	if len(statuses) == 0 {
@@ -725,6 +1771,121 @@ func GetReviewRequestsByUserAndStatus(ctx context.Context, reviewerLogin string,
 	return requests, nil
 }
 
+// GetRecentReviewRequests retrieves a reviewer's most recently created review requests, newest first
+func GetRecentReviewRequests(ctx context.Context, reviewerLogin string, limit int) ([]*models.ReviewRequest, error) {
+	return getRecentReviewRequests(ctx, NewDatabaseAdapter(), reviewerLogin, limit)
+}
+
+// getRecentReviewRequests retrieves a reviewer's most recent review requests through db
+func getRecentReviewRequests(ctx context.Context, db Database, reviewerLogin string, limit int) ([]*models.ReviewRequest, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	sql := TablePathPrefix("") + `
+		DECLARE $reviewer_login AS Utf8;
+		DECLARE $limit AS Uint64;
+
+		SELECT id, reviewer_login, notification_id, project_name, family_label, review_start_time,
+		       calendar_slot_id, booking_id, decision_deadline, non_whitelist_cancel_at, telegram_message_id,
+		       status, created_at, decided_at
+		FROM review_requests
+		WHERE reviewer_login = $reviewer_login
+		ORDER BY created_at DESC
+		LIMIT $limit;
+	`
+
+	params := []table.ParameterOption{
+		table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
+		table.ValueParam("$limit", types.Uint64Value(uint64(limit))),
+	}
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent review requests for %s: %w", reviewerLogin, err)
+	}
+	defer res.Close()
+
+	var requests []*models.ReviewRequest
+	for res.NextRow() {
+		req, err := scanReviewRequest(res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review request: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+// GetAllReviewRequestsByReviewer retrieves a reviewer's full review request history, oldest first
+func GetAllReviewRequestsByReviewer(ctx context.Context, reviewerLogin string) ([]*models.ReviewRequest, error) {
+	return getAllReviewRequestsByReviewer(ctx, NewDatabaseAdapter(), reviewerLogin)
+}
+
+// getAllReviewRequestsByReviewer retrieves every review request of a reviewer through db
+func getAllReviewRequestsByReviewer(ctx context.Context, db Database, reviewerLogin string) ([]*models.ReviewRequest, error) {
+	sql := TablePathPrefix("") + `
+		DECLARE $reviewer_login AS Utf8;
+
+		SELECT id, reviewer_login, notification_id, project_name, family_label, review_start_time,
+		       calendar_slot_id, booking_id, decision_deadline, non_whitelist_cancel_at, telegram_message_id,
+		       status, created_at, decided_at
+		FROM review_requests
+		WHERE reviewer_login = $reviewer_login
+		ORDER BY created_at;
+	`
+
+	params := []table.ParameterOption{
+		table.ValueParam("$reviewer_login", types.TextValue(reviewerLogin)),
+	}
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review requests for %s: %w", reviewerLogin, err)
+	}
+	defer res.Close()
+
+	var requests []*models.ReviewRequest
+	for res.NextRow() {
+		req, err := scanReviewRequest(res)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review request: %w", err)
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
+
+// DeleteReviewRequest deletes a review request by ID; deleting a missing ID is not an error
+func DeleteReviewRequest(ctx context.Context, id string) error {
+	return deleteReviewRequest(ctx, NewDatabaseAdapter(), id)
+}
+
+// deleteReviewRequest deletes a review request by ID through db
+func deleteReviewRequest(ctx context.Context, db Database, id string) error {
+	if id == "" {
+		return fmt.Errorf("review request id is required")
+	}
+
+	sql := TablePathPrefix("") + `
+		DECLARE $id AS Utf8;
+
+		DELETE FROM review_requests
+		WHERE id = $id;
+	`
+
+	params := []table.ParameterOption{
+		table.ValueParam("$id", types.TextValue(id)),
+	}
+
+	if err := db.Exec(ctx, sql, params...); err != nil {
+		return fmt.Errorf("failed to delete review request %s: %w", id, err)
+	}
+	return nil
+}
+
 // GetExpiredWaitingForApprove retrieves reviews that have passed their decision deadline
 func GetExpiredWaitingForApprove(ctx context.Context) ([]*models.ReviewRequest, error) {
 	sql := TablePathPrefix("") + `
@@ -793,8 +1954,150 @@ func GetExpiredNotWhitelisted(ctx context.Context) ([]*models.ReviewRequest, err
 	return requests, nil
 }
 
+// archiveBatchSize bounds how many review requests one archival transaction removes
+const archiveBatchSize = 500
+
+// archiveCandidate is a review request row considered for archival
+type archiveCandidate struct {
+	ID        string `db:"id"`
+	Status    string `db:"status"`
+	CreatedAt uint32 `db:"created_at"`
+}
+
+// ArchiveReviewRequestsBefore removes final-status review requests created before the given unix time
+// Rows are deleted in bounded batches, one transaction per batch; returns the number removed
+func ArchiveReviewRequestsBefore(ctx context.Context, before int64) (int, error) {
+	return archiveReviewRequestsBefore(ctx, NewDatabaseAdapter(), before, archiveBatchSize)
+}
+
+// archiveReviewRequestsBefore removes old final-status review requests through db
+func archiveReviewRequestsBefore(ctx context.Context, db Database, before int64, batchSize int) (int, error) {
+	if before <= 0 || batchSize <= 0 {
+		return 0, nil
+	}
+	cutoff := uint32(before)
+	if before > math.MaxUint32 {
+		cutoff = math.MaxUint32
+	}
+
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		var fetched, deleted int
+		err := db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
+			candidates, err := readArchiveCandidatesTx(ctx, tx, cutoff, batchSize)
+			if err != nil {
+				return err
+			}
+			fetched = len(candidates)
+
+			ids := archivableIDs(candidates, cutoff)
+			deleted = len(ids)
+			if deleted == 0 {
+				return nil
+			}
+
+			return deleteReviewRequestsTx(ctx, tx, ids)
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to archive review requests: %w", err)
+		}
+
+		total += deleted
+		if fetched < batchSize || deleted == 0 {
+			return total, nil
+		}
+	}
+}
+
+// readArchiveCandidatesTx reads up to limit final-status review requests created before cutoff
+func readArchiveCandidatesTx(ctx context.Context, tx table.TransactionActor, cutoff uint32, limit int) ([]archiveCandidate, error) {
+	selectSQL := TablePathPrefix("") + `
+		DECLARE $before AS Datetime;
+		DECLARE $statuses AS List<Utf8>;
+		DECLARE $limit AS Uint64;
+
+		SELECT id, status, created_at
+		FROM review_requests
+		WHERE created_at < $before AND status IN $statuses
+		LIMIT $limit;
+	`
+
+	statuses := make([]types.Value, 0, len(models.FinalStatuses()))
+	for _, status := range models.FinalStatuses() {
+		statuses = append(statuses, types.TextValue(status))
+	}
+
+	res, err := tx.Execute(ctx, selectSQL, table.NewQueryParameters(
+		table.ValueParam("$before", types.DatetimeValue(cutoff)),
+		table.ValueParam("$statuses", types.ListValue(statuses...)),
+		table.ValueParam("$limit", types.Uint64Value(uint64(limit))),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archivable review requests: %w", err)
+	}
+	defer res.Close()
+
+	if err := res.NextResultSetErr(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read archivable review requests: %w", err)
+	}
+
+	var candidates []archiveCandidate
+	for res.NextRow() {
+		var candidate archiveCandidate
+		if err := yscan.ScanRow(&candidate, res); err != nil {
+			return nil, fmt.Errorf("failed to scan archivable review request: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+// deleteReviewRequestsTx deletes the review requests with the given IDs
+func deleteReviewRequestsTx(ctx context.Context, tx table.TransactionActor, ids []string) error {
+	deleteSQL := TablePathPrefix("") + `
+		DECLARE $ids AS List<Utf8>;
+
+		DELETE FROM review_requests
+		WHERE id IN $ids;
+	`
+
+	values := make([]types.Value, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, types.TextValue(id))
+	}
+
+	_, err := tx.Execute(ctx, deleteSQL, table.NewQueryParameters(
+		table.ValueParam("$ids", types.ListValue(values...)),
+	))
+	if err != nil {
+		return fmt.Errorf("failed to delete review requests: %w", err)
+	}
+	return nil
+}
+
+// archivableIDs returns IDs of candidates with a final status created before cutoff
+func archivableIDs(candidates []archiveCandidate, cutoff uint32) []string {
+	var ids []string
+	for _, candidate := range candidates {
+		if models.IsFinalStatus(candidate.Status) && candidate.CreatedAt < cutoff {
+			ids = append(ids, candidate.ID)
+		}
+	}
+	return ids
+}
+
 // UpdateReviewRequestStatus updates a review request's status
 func UpdateReviewRequestStatus(ctx context.Context, id, status string, decidedAt *uint32) error {
+	return updateReviewRequestStatus(ctx, NewDatabaseAdapter(), id, status, decidedAt)
+}
+
+// updateReviewRequestStatus updates a review request's status through db
+func updateReviewRequestStatus(ctx context.Context, db Database, id, status string, decidedAt *uint32) error {
 	sql := TablePathPrefix("") + `
 		DECLARE $id AS Utf8;
 		DECLARE $status AS Utf8;
@@ -811,7 +2114,165 @@ func UpdateReviewRequestStatus(ctx context.Context, id, status string, decidedAt
 		table.ValueParam("$decided_at", optionalDatetime(decidedAt)),
 	}
 
-	return Exec(ctx, sql, params...)
+	return db.Exec(ctx, sql, params...)
+}
+
+// UpdateReviewRequestStatusChecked updates a review request's status only if the
+// transition from the current status is allowed by models.CanTransition
+func UpdateReviewRequestStatusChecked(ctx context.Context, id, status string, decidedAt *uint32) error {
+	return updateReviewRequestStatusChecked(ctx, NewDatabaseAdapter(), id, status, decidedAt)
+}
+
+// updateReviewRequestStatusChecked reads the current status and updates it through db in one transaction
+func updateReviewRequestStatusChecked(ctx context.Context, db Database, id, status string, decidedAt *uint32) error {
+	return db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
+		currentStatus, found, err := readReviewRequestStatusTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("review request not found: %s", id)
+		}
+
+		if err := validateStatusUpdate(id, currentStatus, status); err != nil {
+			return err
+		}
+
+		updateSQL := TablePathPrefix("") + `
+			DECLARE $id AS Utf8;
+			DECLARE $status AS Utf8;
+			DECLARE $decided_at AS Optional<Datetime>;
+
+			UPDATE review_requests
+			SET status = $status, decided_at = $decided_at
+			WHERE id = $id;
+		`
+
+		_, err = tx.Execute(ctx, updateSQL, table.NewQueryParameters(
+			table.ValueParam("$id", types.TextValue(id)),
+			table.ValueParam("$status", types.TextValue(status)),
+			table.ValueParam("$decided_at", optionalDatetime(decidedAt)),
+		))
+		if err != nil {
+			return fmt.Errorf("failed to update status of review request %s: %w", id, err)
+		}
+
+		return nil
+	})
+}
+
+// ClaimExpiredReview moves a review request from expectedStatus to newStatus and
+// reports whether this caller won the claim. Workers call it before acting on an
+// expired review so that concurrent replicas never process the same one twice.
+func ClaimExpiredReview(ctx context.Context, id, expectedStatus, newStatus string) (bool, error) {
+	return claimExpiredReview(ctx, NewDatabaseAdapter(), id, expectedStatus, newStatus)
+}
+
+// claimExpiredReview claims the review request on db.
+//
+// YDB's UPDATE does not report how many rows it changed, so the claim cannot be
+// decided by a rows-affected check. Instead the status is read and conditionally
+// updated inside one serializable transaction: if another replica commits its claim
+// after our read, YDB aborts our commit with a lock conflict and DoTx retries the
+// whole function, whose second read then sees the new status and gives up the claim.
+func claimExpiredReview(ctx context.Context, db Database, id, expectedStatus, newStatus string) (bool, error) {
+	if err := validateStatusUpdate(id, expectedStatus, newStatus); err != nil {
+		return false, err
+	}
+
+	var claimed bool
+	err := db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
+		read := func() (string, bool, error) {
+			return readReviewRequestStatusTx(ctx, tx, id)
+		}
+		write := func() error {
+			updateSQL := TablePathPrefix("") + `
+				DECLARE $id AS Utf8;
+				DECLARE $expected_status AS Utf8;
+				DECLARE $status AS Utf8;
+
+				UPDATE review_requests
+				SET status = $status
+				WHERE id = $id AND status = $expected_status;
+			`
+
+			_, err := tx.Execute(ctx, updateSQL, table.NewQueryParameters(
+				table.ValueParam("$id", types.TextValue(id)),
+				table.ValueParam("$expected_status", types.TextValue(expectedStatus)),
+				table.ValueParam("$status", types.TextValue(newStatus)),
+			))
+			if err != nil {
+				return fmt.Errorf("failed to claim review request %s: %w", id, err)
+			}
+			return nil
+		}
+
+		var err error
+		claimed, err = claimReview(expectedStatus, read, write)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return claimed, nil
+}
+
+// claimReview writes the claim only if the current status still equals expectedStatus
+func claimReview(expectedStatus string, read func() (string, bool, error), write func() error) (bool, error) {
+	currentStatus, found, err := read()
+	if err != nil {
+		return false, err
+	}
+	if !found || currentStatus != expectedStatus {
+		return false, nil
+	}
+
+	if err := write(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// readReviewRequestStatusTx reads the current status of a review request inside a transaction
+func readReviewRequestStatusTx(ctx context.Context, tx table.TransactionActor, id string) (string, bool, error) {
+	selectSQL := TablePathPrefix("") + `
+		DECLARE $id AS Utf8;
+
+		SELECT status
+		FROM review_requests
+		WHERE id = $id;
+	`
+
+	res, err := tx.Execute(ctx, selectSQL, table.NewQueryParameters(
+		table.ValueParam("$id", types.TextValue(id)),
+	))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read status of review request %s: %w", id, err)
+	}
+	defer res.Close()
+
+	if err := res.NextResultSetErr(ctx); err != nil {
+		return "", false, fmt.Errorf("failed to read status of review request %s: %w", id, err)
+	}
+	if !res.NextRow() {
+		return "", false, nil
+	}
+
+	var status string
+	if err := yscan.ScanRow(&status, res); err != nil {
+		return "", false, fmt.Errorf("failed to scan review request status: %w", err)
+	}
+
+	return status, true, nil
+}
+
+// validateStatusUpdate rejects a status change that violates the transition rules
+func validateStatusUpdate(id, currentStatus, newStatus string) error {
+	if err := models.ValidateTransition(currentStatus, newStatus); err != nil {
+		return fmt.Errorf("review request %s: %w", id, err)
+	}
+	return nil
 }
 
 // UpdateReviewRequestWithProjectInfo updates a review request with project info
@@ -842,8 +2303,48 @@ func UpdateReviewRequestWithProjectInfo(ctx context.Context, id, projectName, fa
 	return Exec(ctx, sql, params...)
 }
 
+// DefaultMinDecisionDeadlineLead is how far in the future a decision deadline must be by default
+const DefaultMinDecisionDeadlineLead = 5 * time.Second
+
+// minDecisionDeadlineLead holds the configured minimum decision deadline lead as a time.Duration
+var minDecisionDeadlineLead atomic.Int64
+
+func init() {
+	minDecisionDeadlineLead.Store(int64(DefaultMinDecisionDeadlineLead))
+}
+
+// SetMinDecisionDeadlineLead sets how far in the future a decision deadline must be when a
+// review request moves to WAITING_FOR_APPROVE. A negative value is treated as zero.
+func SetMinDecisionDeadlineLead(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	minDecisionDeadlineLead.Store(int64(d))
+}
+
+// validateDecisionDeadline rejects deadlines closer to now than the configured minimum lead
+func validateDecisionDeadline(decisionDeadline uint32, now time.Time) error {
+	lead := time.Duration(minDecisionDeadlineLead.Load())
+	earliest := now.Add(lead)
+	if time.Unix(int64(decisionDeadline), 0).Before(earliest) {
+		return fmt.Errorf("decision deadline %s must be at least %s in the future",
+			time.Unix(int64(decisionDeadline), 0).UTC().Format(time.RFC3339), lead)
+	}
+	return nil
+}
+
 // UpdateReviewRequestToWaitingForApprove updates a review request to WAITING_FOR_APPROVE
+// The decision deadline must be at least the configured minimum lead in the future
 func UpdateReviewRequestToWaitingForApprove(ctx context.Context, id string, decisionDeadline uint32, telegramMessageID string) error {
+	return updateReviewRequestToWaitingForApprove(ctx, NewDatabaseAdapter(), id, decisionDeadline, telegramMessageID)
+}
+
+// updateReviewRequestToWaitingForApprove moves a review request to WAITING_FOR_APPROVE through db
+func updateReviewRequestToWaitingForApprove(ctx context.Context, db Database, id string, decisionDeadline uint32, telegramMessageID string) error {
+	if err := validateDecisionDeadline(decisionDeadline, time.Now()); err != nil {
+		return fmt.Errorf("invalid decision deadline for review request %s: %w", id, err)
+	}
+
 	sql := TablePathPrefix("") + `
 		DECLARE $id AS Utf8;
 		DECLARE $decision_deadline AS Datetime;
@@ -864,7 +2365,7 @@ func UpdateReviewRequestToWaitingForApprove(ctx context.Context, id string, deci
 		table.ValueParam("$status", types.TextValue(models.StatusWaitingForApprove)),
 	}
 
-	return Exec(ctx, sql, params...)
+	return db.Exec(ctx, sql, params...)
 }
 
 // UpdateReviewRequestToNotWhitelisted updates a review request to NOT_WHITELISTED
@@ -948,6 +2449,9 @@ func GetUserTokens(ctx context.Context, reviewerLogin string) (*models.UserToken
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user tokens: %w", err)
 		}
+		if err := ensureSingleRow(res, "user_tokens for "+reviewerLogin); err != nil {
+			return nil, err
+		}
 		return &tokens, nil
 	}
 
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/ydb-platform/ydb-go-sdk/v3"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table"
@@ -18,8 +21,62 @@ import (
 var (
 	db   *ydb.Driver
 	once sync.Once
+
+	// defaultQueryTimeout bounds Query/Exec calls whose context has no deadline (0 disables)
+	defaultQueryTimeout atomic.Int64
+
+	// queryObserver is notified after every Query/Exec/DoTx (nil means no-op)
+	queryObserver atomic.Pointer[QueryObserver]
+)
+
+// Operation names reported to the QueryObserver
+const (
+	OpQuery = "Query"
+	OpExec  = "Exec"
+	OpDoTx  = "DoTx"
 )
 
+// QueryObserver receives the operation name, SQL text (empty for DoTx),
+// elapsed duration and resulting error of a database call
+type QueryObserver func(op, sql string, dur time.Duration, err error)
+
+// SetQueryObserver installs an observer for metrics or tracing. Passing nil removes it.
+func SetQueryObserver(observer QueryObserver) {
+	if observer == nil {
+		queryObserver.Store(nil)
+		return
+	}
+	queryObserver.Store(&observer)
+}
+
+// observeQuery reports a finished database call to the installed observer
+func observeQuery(op, sql string, start time.Time, err error) {
+	if observer := queryObserver.Load(); observer != nil {
+		(*observer)(op, sql, time.Since(start), err)
+	}
+}
+
+// SetDefaultQueryTimeout sets the timeout applied to Query and Exec when the
+// caller's context has no deadline. A zero or negative value disables it.
+func SetDefaultQueryTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	defaultQueryTimeout.Store(int64(d))
+}
+
+// withDefaultTimeout bounds ctx by the default query timeout if it has no deadline
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(defaultQueryTimeout.Load())
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, hasDeadline := ctx.Deadline(); hasDeadline {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // GetConnection returns a YDB connection, creating it if needed
 func GetConnection(ctx context.Context) (*ydb.Driver, error) {
 	var initErr error
@@ -41,10 +98,12 @@ func GetConnection(ctx context.Context) (*ydb.Driver, error) {
 		connectionString := endpoint + "/?database=" + database
 		log.Printf("[YDB] Connection string: %s", connectionString)
 
-		db, initErr = ydb.Open(ctx, connectionString,
-			yc.WithCredentials(), // Use instance metadata service for authentication
-			yc.WithInternalCA(),  // Append Yandex Cloud certificates
-		)
+		db, initErr = connectWithRetry(ctx, connectAttempts, connectBackoff, func(ctx context.Context) (*ydb.Driver, error) {
+			return ydb.Open(ctx, connectionString,
+				yc.WithCredentials(), // Use instance metadata service for authentication
+				yc.WithInternalCA(),  // Append Yandex Cloud certificates
+			)
+		})
 
 		if initErr != nil {
 			log.Printf("[YDB] Failed to open connection: %v", initErr)
@@ -60,6 +119,41 @@ func GetConnection(ctx context.Context) (*ydb.Driver, error) {
 	return db, initErr
 }
 
+// Initial connection retry policy: attempts in total and the first backoff, doubled after each failure
+const (
+	connectAttempts = 4
+	connectBackoff  = 500 * time.Millisecond
+)
+
+// connectWithRetry calls connect up to attempts times with exponential backoff, honouring ctx
+// It returns the last error when all attempts fail
+func connectWithRetry(ctx context.Context, attempts int, backoff time.Duration, connect func(ctx context.Context) (*ydb.Driver, error)) (*ydb.Driver, error) {
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		driver, err := connect(ctx)
+		if err == nil {
+			return driver, nil
+		}
+		lastErr = err
+		log.Printf("[YDB] Connection attempt %d/%d failed: %v", attempt, attempts, err)
+
+		if attempt == attempts {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("connection aborted after %d attempts: %w", attempt, ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+
+	return nil, fmt.Errorf("failed to connect after %d attempts: %w", attempts, lastErr)
+}
+
 // CloseConnection closes the YDB connection (no-op for singleton model)
 func CloseConnection(ctx context.Context) error {
 	// No-op - connection is managed as singleton
@@ -68,6 +162,43 @@ func CloseConnection(ctx context.Context) error {
 
 // Query executes a query and returns the result set
 func Query(ctx context.Context, sql string, params ...table.ParameterOption) (result.Result, error) {
+	start := time.Now()
+	res, err := query(ctx, driverDatabase{}, sql, params...)
+	observeQuery(OpQuery, sql, start, err)
+	return res, err
+}
+
+// query executes a query on db, joining the transaction from ctx if there is one,
+// and bounds it by the default query timeout
+func query(ctx context.Context, db Database, sql string, params ...table.ParameterOption) (result.Result, error) {
+	if tx, ok := txFromContext(ctx); ok {
+		return queryTx(ctx, tx, sql, params...)
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	return db.Query(ctx, sql, params...)
+}
+
+// exec executes a query that doesn't return results on db, joining the transaction
+// from ctx if there is one, and bounds it by the default query timeout
+func exec(ctx context.Context, db Database, sql string, params ...table.ParameterOption) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return execTx(ctx, tx, sql, params...)
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+
+	return db.Exec(ctx, sql, params...)
+}
+
+// driverDatabase implements the Database interface directly on the global driver
+type driverDatabase struct{}
+
+// Query executes a query and returns the result set
+func (driverDatabase) Query(ctx context.Context, sql string, params ...table.ParameterOption) (result.Result, error) {
 	driver, err := GetConnection(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get YDB connection: %w", err)
@@ -102,6 +233,14 @@ func Query(ctx context.Context, sql string, params ...table.ParameterOption) (re
 
 // Exec executes a query that doesn't return results
 func Exec(ctx context.Context, sql string, params ...table.ParameterOption) error {
+	start := time.Now()
+	err := exec(ctx, driverDatabase{}, sql, params...)
+	observeQuery(OpExec, sql, start, err)
+	return err
+}
+
+// Exec executes a query that doesn't return results
+func (driverDatabase) Exec(ctx context.Context, sql string, params ...table.ParameterOption) error {
 	driver, err := GetConnection(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get YDB connection: %w", err)
@@ -136,6 +275,16 @@ func Exec(ctx context.Context, sql string, params ...table.ParameterOption) erro
 	return err
 }
 
+// DoTx executes a function within a transaction
+func (driverDatabase) DoTx(ctx context.Context, fn func(ctx context.Context, tx table.TransactionActor) error) error {
+	return doTx(ctx, fn)
+}
+
+// Close closes the database connection
+func (driverDatabase) Close(ctx context.Context) error {
+	return CloseConnection(ctx)
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -145,6 +294,18 @@ func truncateString(s string, maxLen int) string {
 
 // DoTx executes a function within a transaction
 func DoTx(ctx context.Context, fn func(ctx context.Context, tx table.TransactionActor) error) error {
+	start := time.Now()
+	err := doTx(ctx, fn)
+	observeQuery(OpDoTx, "", start, err)
+	return err
+}
+
+// doTx executes a function within a transaction
+func doTx(ctx context.Context, fn func(ctx context.Context, tx table.TransactionActor) error) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return fn(ctx, tx)
+	}
+
 	driver, err := GetConnection(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to get YDB connection: %w", err)
@@ -155,6 +316,54 @@ func DoTx(ctx context.Context, fn func(ctx context.Context, tx table.Transaction
 	}, table.WithIdempotent())
 }
 
+// txContextKey is the context key holding the transaction opened by WithTx
+type txContextKey struct{}
+
+// WithTx runs fn inside one transaction. Query, Exec and DoTx calls made with the
+// context passed to fn, including those inside repository functions, join that
+// transaction, so they commit or roll back together.
+func WithTx(ctx context.Context, fn func(txCtx context.Context) error) error {
+	return withTx(ctx, NewDatabaseAdapter(), fn)
+}
+
+// withTx runs fn inside a transaction opened through db
+func withTx(ctx context.Context, db Database, fn func(txCtx context.Context) error) error {
+	return db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error {
+		return fn(context.WithValue(ctx, txContextKey{}, tx))
+	})
+}
+
+// txFromContext returns the transaction opened by WithTx, if any
+func txFromContext(ctx context.Context) (table.TransactionActor, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(table.TransactionActor)
+	return tx, ok
+}
+
+// queryTx executes a query inside an existing transaction
+func queryTx(ctx context.Context, tx table.TransactionActor, sql string, params ...table.ParameterOption) (result.Result, error) {
+	res, err := tx.Execute(ctx, sql, table.NewQueryParameters(params...))
+	if err != nil {
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	if err := res.NextResultSetErr(ctx); err != nil {
+		res.Close()
+		return nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	return res, nil
+}
+
+// execTx executes a query that doesn't return results inside an existing transaction
+func execTx(ctx context.Context, tx table.TransactionActor, sql string, params ...table.ParameterOption) error {
+	res, err := tx.Execute(ctx, sql, table.NewQueryParameters(params...))
+	if err != nil {
+		return err
+	}
+	if err = res.Err(); err != nil {
+		return err
+	}
+	return res.Close()
+}
+
 // NewParameter creates a new query parameter
 func NewParameter(name string, value any) table.ParameterOption {
 	return table.ValueParam(name, value.(types.Value))
@@ -172,3 +381,59 @@ func TablePathPrefix(path string) string {
 	}
 	return fmt.Sprintf("PRAGMA TablePathPrefix(\"%s\");", path)
 }
+
+// QueryPaged runs one page of a keyset-paginated query and returns the next cursor
+// baseSQL is a SELECT without DECLARE or trailing semicolon; rows are ordered by keyColumn
+// and only rows with keyColumn greater than cursor are returned. The next cursor is empty
+// when there are no more pages.
+// The cursor is bound as a Utf8 parameter, so keyColumn must be a Utf8 column; an Int64
+// or Timestamp key fails at query time with a type mismatch.
+func QueryPaged[T any](ctx context.Context, baseSQL, keyColumn string, scan func(result.Result) (T, error), key func(T) string, limit int, cursor string) ([]T, string, error) {
+	return queryPaged(ctx, NewDatabaseAdapter(), baseSQL, keyColumn, scan, key, limit, cursor)
+}
+
+// queryPaged runs one page of a keyset-paginated query through db
+func queryPaged[T any](ctx context.Context, db Database, baseSQL, keyColumn string, scan func(result.Result) (T, error), key func(T) string, limit int, cursor string) ([]T, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("page limit must be positive, got %d", limit)
+	}
+
+	sql := TablePathPrefix("") + fmt.Sprintf(`
+		DECLARE $cursor AS Utf8;
+		DECLARE $limit AS Uint64;
+
+		SELECT * FROM (
+			%s
+		)
+		WHERE %s > $cursor
+		ORDER BY %s
+		LIMIT $limit;
+	`, strings.TrimSuffix(strings.TrimSpace(baseSQL), ";"), keyColumn, keyColumn)
+
+	params := []table.ParameterOption{
+		table.ValueParam("$cursor", types.TextValue(cursor)),
+		table.ValueParam("$limit", types.Uint64Value(uint64(limit))),
+	}
+
+	res, err := db.Query(ctx, sql, params...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Close()
+
+	var items []T
+	for res.NextRow() {
+		item, err := scan(res)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+	}
+
+	next := ""
+	if len(items) == limit {
+		next = key(items[len(items)-1])
+	}
+
+	return items, next, nil
+}
@@ -5,13 +5,21 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/ydb-platform/ydb-go-sdk/v3"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table"
+	"github.com/ydb-platform/ydb-go-sdk/v3/table/options"
+	"github.com/ydb-platform/ydb-go-sdk/v3/table/result"
+	"github.com/ydb-platform/ydb-go-sdk/v3/table/result/named"
 	"github.com/ydb-platform/ydb-go-sdk/v3/table/types"
 
 	"github.com/arseniisemenow/review-slot-guard-bot-common/pkg/models"
@@ -205,6 +213,202 @@ func TestDatabaseAdapter_Close(t *testing.T) {
 	})
 }
 
+// TestDatabaseAdapter_RepositoryMethods drives the Repository methods of DatabaseAdapter
+// inside a fake transaction, so their statements run through the real Query/Exec path
+func TestDatabaseAdapter_RepositoryMethods(t *testing.T) {
+	ctx := context.Background()
+	var repo Repository = NewDatabaseAdapter()
+	req := &models.ReviewRequest{
+		ID:              "550e8400-e29b-41d4-a716-446655440000",
+		ReviewerLogin:   "testuser",
+		CalendarSlotID:  "slot-123",
+		BookingID:       "booking-123",
+		ReviewStartTime: 1700003600,
+		Status:          models.StatusUnknownProjectReview,
+		CreatedAt:       1700000000,
+	}
+
+	t.Run("create and update run in the caller's transaction", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+		decidedAt := uint32(1700000100)
+
+		err := withTx(ctx, db, func(txCtx context.Context) error {
+			if err := repo.CreateReviewRequest(txCtx, req); err != nil {
+				return err
+			}
+			return repo.UpdateReviewRequestStatus(txCtx, req.ID, models.StatusCancelled, &decidedAt)
+		})
+
+		require.NoError(t, err)
+		require.Len(t, db.committed, 2)
+		assert.Contains(t, db.committed[0], "INSERT INTO review_requests")
+		assert.Contains(t, db.committed[1], "UPDATE review_requests")
+	})
+
+	t.Run("get by id scans the row", func(t *testing.T) {
+		db := &fakeTxDatabase{results: func(sql string) result.Result {
+			return &fakeNamedResult{rows: []map[string]any{{
+				"id":               req.ID,
+				"reviewer_login":   req.ReviewerLogin,
+				"calendar_slot_id": req.CalendarSlotID,
+				"status":           req.Status,
+			}}}
+		}}
+
+		var got *models.ReviewRequest
+		err := withTx(ctx, db, func(txCtx context.Context) error {
+			var err error
+			got, err = repo.GetReviewRequestByID(txCtx, req.ID)
+			return err
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, req.ID, got.ID)
+		assert.Equal(t, "slot-123", got.CalendarSlotID)
+		assert.Contains(t, db.committed[0], "WHERE id = $id")
+	})
+
+	t.Run("failed update rolls back the create", func(t *testing.T) {
+		db := &fakeTxDatabase{failOn: "UPDATE review_requests"}
+
+		err := withTx(ctx, db, func(txCtx context.Context) error {
+			if err := repo.CreateReviewRequest(txCtx, req); err != nil {
+				return err
+			}
+			return repo.UpdateReviewRequestStatus(txCtx, req.ID, models.StatusCancelled, nil)
+		})
+
+		assert.Error(t, err)
+		assert.Empty(t, db.committed)
+	})
+}
+
+// TestMockRepository tests that services can inject a Repository mock
+func TestMockRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMockRepository()
+	req := &models.ReviewRequest{ID: "req-1", Status: models.StatusWaitingForApprove}
+
+	repo.On("GetReviewRequestByID", ctx, "req-1").Return(req, nil).Once()
+	repo.On("UpdateReviewRequestStatus", ctx, "req-1", models.StatusApproved, (*uint32)(nil)).Return(nil).Once()
+
+	// approve is a minimal service that depends only on Repository
+	approve := func(r Repository, id string) error {
+		current, err := r.GetReviewRequestByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != models.StatusWaitingForApprove {
+			return fmt.Errorf("review request %s is %s", id, current.Status)
+		}
+		return r.UpdateReviewRequestStatus(ctx, id, models.StatusApproved, nil)
+	}
+
+	require.NoError(t, approve(repo, "req-1"))
+	repo.AssertExpectations(t)
+}
+
+// TestRepositoryOperations_MockDatabase tests repository operations routed through a Database
+func TestRepositoryOperations_MockDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create review request executes insert", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		req := &models.ReviewRequest{
+			ID:             "550e8400-e29b-41d4-a716-446655440000",
+			ReviewerLogin:  "testuser",
+			CalendarSlotID: "slot-123",
+			BookingID:      "booking-123",
+			Status:         models.StatusUnknownProjectReview,
+		}
+		mockDB.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "INSERT INTO review_requests", "$calendar_slot_id")
+		}), mock.Anything).Return(nil).Once()
+
+		err := createReviewRequest(ctx, mockDB, req)
+
+		assert.NoError(t, err)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("create review request rejects invalid request without executing", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		err := createReviewRequest(ctx, mockDB, &models.ReviewRequest{ID: "id"})
+
+		assert.Error(t, err)
+		mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("create review request rejects non-UUID id without executing", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		req := &models.ReviewRequest{
+			ID:             "not-a-uuid",
+			ReviewerLogin:  "testuser",
+			CalendarSlotID: "slot-123",
+			Status:         models.StatusUnknownProjectReview,
+		}
+
+		err := createReviewRequest(ctx, mockDB, req)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), models.ErrInvalidReviewID)
+		mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("update review request status propagates exec error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		execErr := errors.New("exec failed")
+		mockDB.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "UPDATE review_requests", "SET status = $status")
+		}), mock.Anything).Return(execErr).Once()
+
+		err := updateReviewRequestStatus(ctx, mockDB, "req-1", models.StatusApproved, nil)
+
+		assert.ErrorIs(t, err, execErr)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("get review request by ID wraps query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "FROM review_requests", "WHERE id = $id")
+		}), mock.Anything).Return(nil, queryErr).Once()
+
+		req, err := getReviewRequestByID(ctx, mockDB, "req-1")
+
+		assert.Nil(t, req)
+		assert.ErrorIs(t, err, queryErr)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("get active users wraps query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "FROM users", `status = "ACTIVE"`)
+		}), mock.Anything).Return(nil, queryErr).Once()
+
+		users, err := getActiveUsers(ctx, mockDB)
+
+		assert.Nil(t, users)
+		assert.ErrorIs(t, err, queryErr)
+		assert.Contains(t, err.Error(), "failed to query active users")
+		mockDB.AssertExpectations(t)
+	})
+}
+
+// containsAll reports whether s contains every substring
+func containsAll(s string, substrs ...string) bool {
+	for _, substr := range substrs {
+		if findSubstring(s, substr, 0) == -1 {
+			return false
+		}
+	}
+	return true
+}
+
 // ============================================================================
 // Helper functions for environment variable testing
 // ============================================================================
@@ -403,6 +607,179 @@ func TestGetConnection_MissingEnvVars(t *testing.T) {
 	}
 }
 
+// TestDefaultQueryTimeout tests that queries without a deadline are bounded by the default timeout
+func TestDefaultQueryTimeout(t *testing.T) {
+	defer SetDefaultQueryTimeout(0)
+
+	// slowQuery simulates a stalled YDB session that only returns when its context is done
+	slowQuery := func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+
+	t.Run("long-running query is cancelled at the timeout", func(t *testing.T) {
+		SetDefaultQueryTimeout(20 * time.Millisecond)
+
+		ctx, cancel := withDefaultTimeout(context.Background())
+		defer cancel()
+
+		start := time.Now()
+		err := slowQuery(ctx)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("zero timeout disables the bound", func(t *testing.T) {
+		SetDefaultQueryTimeout(0)
+
+		ctx, cancel := withDefaultTimeout(context.Background())
+		defer cancel()
+
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+	})
+
+	t.Run("existing deadline is kept", func(t *testing.T) {
+		SetDefaultQueryTimeout(time.Hour)
+
+		parent, parentCancel := context.WithTimeout(context.Background(), time.Minute)
+		defer parentCancel()
+		expected, _ := parent.Deadline()
+
+		ctx, cancel := withDefaultTimeout(parent)
+		defer cancel()
+
+		deadline, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		assert.Equal(t, expected, deadline)
+	})
+
+	t.Run("query and exec without a deadline time out on a stalled database", func(t *testing.T) {
+		SetDefaultQueryTimeout(20 * time.Millisecond)
+
+		stalled := &stalledDatabase{wait: slowQuery}
+
+		start := time.Now()
+		_, err := query(context.Background(), stalled, "SELECT 1")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		err = exec(context.Background(), stalled, "DELETE FROM t")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+// stalledDatabase is a Database whose Query and Exec block until their context is done
+type stalledDatabase struct {
+	MockDatabase
+	wait func(ctx context.Context) error
+}
+
+func (s *stalledDatabase) Query(ctx context.Context, sql string, params ...table.ParameterOption) (result.Result, error) {
+	return nil, s.wait(ctx)
+}
+
+func (s *stalledDatabase) Exec(ctx context.Context, sql string, params ...table.ParameterOption) error {
+	return s.wait(ctx)
+}
+
+// TestQueryObserver tests that the observer is notified with op name, duration and error
+func TestQueryObserver(t *testing.T) {
+	defer SetQueryObserver(nil)
+
+	type observation struct {
+		op  string
+		sql string
+		dur time.Duration
+		err error
+	}
+	var observed []observation
+	SetQueryObserver(func(op, sql string, dur time.Duration, err error) {
+		observed = append(observed, observation{op: op, sql: sql, dur: dur, err: err})
+	})
+
+	t.Run("query, exec and tx report op and duration", func(t *testing.T) {
+		observed = nil
+		db := &fakeTxDatabase{results: func(sql string) result.Result {
+			time.Sleep(time.Millisecond)
+			return &fakeRowsResult{}
+		}}
+
+		err := withTx(context.Background(), db, func(txCtx context.Context) error {
+			if _, err := Query(txCtx, "SELECT * FROM users"); err != nil {
+				return err
+			}
+			if err := Exec(txCtx, "UPDATE users SET status = $status"); err != nil {
+				return err
+			}
+			return DoTx(txCtx, func(ctx context.Context, tx table.TransactionActor) error {
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+		})
+		require.NoError(t, err)
+
+		require.Len(t, observed, 3)
+		assert.Equal(t, OpQuery, observed[0].op)
+		assert.Equal(t, "SELECT * FROM users", observed[0].sql)
+		assert.Equal(t, OpExec, observed[1].op)
+		assert.Equal(t, OpDoTx, observed[2].op)
+		for _, o := range observed {
+			assert.Greater(t, o.dur, time.Duration(0), o.op)
+			assert.NoError(t, o.err, o.op)
+		}
+	})
+
+	t.Run("failed exec reports error", func(t *testing.T) {
+		observed = nil
+		db := &fakeTxDatabase{failOn: "UPDATE users"}
+
+		err := withTx(context.Background(), db, func(txCtx context.Context) error {
+			return Exec(txCtx, "UPDATE users SET status = $status")
+		})
+		require.Error(t, err)
+
+		require.Len(t, observed, 1)
+		assert.Equal(t, OpExec, observed[0].op)
+		assert.Error(t, observed[0].err)
+	})
+
+	t.Run("YDBClient calls are observed", func(t *testing.T) {
+		observed = nil
+		var db Database = &YDBClient{}
+		ctx := context.Background()
+
+		_, _ = db.Query(ctx, "SELECT * FROM users")
+		_ = db.Exec(ctx, "UPDATE users SET status = $status")
+		_ = db.DoTx(ctx, func(ctx context.Context, tx table.TransactionActor) error { return nil })
+
+		require.Len(t, observed, 3)
+		assert.Equal(t, OpQuery, observed[0].op)
+		assert.Equal(t, "SELECT * FROM users", observed[0].sql)
+		assert.Equal(t, OpExec, observed[1].op)
+		assert.Equal(t, "UPDATE users SET status = $status", observed[1].sql)
+		assert.Equal(t, OpDoTx, observed[2].op)
+		for _, o := range observed {
+			assert.ErrorIs(t, o.err, errDriverNotInitialized, o.op)
+		}
+	})
+
+	t.Run("nil observer is a no-op", func(t *testing.T) {
+		observed = nil
+		SetQueryObserver(nil)
+
+		assert.NotPanics(t, func() {
+			observeQuery(OpDoTx, "", time.Now(), nil)
+		})
+		assert.Empty(t, observed)
+	})
+}
+
 // TestQuery_Construction tests SQL query construction logic
 func TestQuery_Construction(t *testing.T) {
 	tests := []struct {
@@ -641,6 +1018,136 @@ func TestSQLValidation(t *testing.T) {
 	}
 }
 
+// TestAddToWhitelistSQL_IsIdempotent tests that whitelist writes use UPSERT so adding
+// the same (reviewer_login, entry_type, name) twice keeps exactly one row
+func TestAddToWhitelistSQL_IsIdempotent(t *testing.T) {
+	assert.Contains(t, upsertWhitelistEntrySQL, "UPSERT INTO user_project_whitelist")
+	assert.NotContains(t, upsertWhitelistEntrySQL, "INSERT INTO")
+	assert.Equal(t, 3, countParameters(upsertWhitelistEntrySQL))
+}
+
+// fakeWhitelistDatabase keeps user_project_whitelist rows in memory, applying the
+// UPSERT and DELETE statements issued by the whitelist repository functions
+type fakeWhitelistDatabase struct {
+	MockDatabase
+	rows []*models.WhitelistEntry
+}
+
+func (d *fakeWhitelistDatabase) Query(ctx context.Context, sql string, params ...table.ParameterOption) (result.Result, error) {
+	login := textParam(params, "$reviewer_login")
+	res := &fakeNamedResult{}
+	for _, row := range d.rows {
+		if row.ReviewerLogin == login {
+			res.rows = append(res.rows, map[string]any{
+				"reviewer_login": row.ReviewerLogin,
+				"entry_type":     row.EntryType,
+				"name":           row.Name,
+			})
+		}
+	}
+	return res, nil
+}
+
+func (d *fakeWhitelistDatabase) Exec(ctx context.Context, sql string, params ...table.ParameterOption) error {
+	login, entryType, name := textParam(params, "$reviewer_login"), textParam(params, "$entry_type"), textParam(params, "$name")
+	switch {
+	case strings.Contains(sql, "UPSERT INTO user_project_whitelist"):
+		for _, row := range d.rows {
+			if row.ReviewerLogin == login && row.EntryType == entryType && row.Name == name {
+				return nil
+			}
+		}
+		d.rows = append(d.rows, &models.WhitelistEntry{ReviewerLogin: login, EntryType: entryType, Name: name})
+	case strings.Contains(sql, "DELETE FROM user_project_whitelist"):
+		var kept []*models.WhitelistEntry
+		for _, row := range d.rows {
+			if row.ReviewerLogin != login || row.Name != name {
+				kept = append(kept, row)
+			}
+		}
+		d.rows = kept
+	default:
+		return fmt.Errorf("unexpected statement: %s", sql)
+	}
+	return nil
+}
+
+func (d *fakeWhitelistDatabase) DoTx(ctx context.Context, fn func(ctx context.Context, tx table.TransactionActor) error) error {
+	return fn(ctx, &fakeTx{})
+}
+
+// textParam returns the Go string value of a Utf8 parameter, or "" if absent
+func textParam(params []table.ParameterOption, name string) string {
+	literal := paramValue(params, name)
+	if literal == "" {
+		return ""
+	}
+	value, err := strconv.Unquote(strings.TrimSuffix(literal, "u"))
+	if err != nil {
+		return literal
+	}
+	return value
+}
+
+func TestAddToWhitelist_Twice(t *testing.T) {
+	ctx := context.Background()
+	db := &fakeWhitelistDatabase{}
+	newEntry := func() *models.WhitelistEntry {
+		return &models.WhitelistEntry{ReviewerLogin: "testuser", EntryType: models.EntryTypeProject, Name: "DO1_Linux"}
+	}
+
+	require.NoError(t, addToWhitelist(ctx, db, newEntry()))
+	require.NoError(t, addToWhitelist(ctx, db, newEntry()))
+
+	require.Len(t, db.rows, 1)
+	assert.Equal(t, newEntry(), db.rows[0])
+
+	t.Run("names differing in case and spacing", func(t *testing.T) {
+		db := &fakeWhitelistDatabase{}
+
+		require.NoError(t, addToWhitelist(ctx, db, &models.WhitelistEntry{
+			ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "Go - I",
+		}))
+		require.NoError(t, addToWhitelist(ctx, db, &models.WhitelistEntry{
+			ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "go-i",
+		}))
+
+		require.Len(t, db.rows, 1)
+		assert.Equal(t, "Go - I", db.rows[0].Name)
+	})
+}
+
+// TestRemoveFromWhitelist tests that removal matches names the way they are stored
+func TestRemoveFromWhitelist(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("remove a differently spelled name", func(t *testing.T) {
+		db := &fakeWhitelistDatabase{}
+		require.NoError(t, addToWhitelist(ctx, db, &models.WhitelistEntry{
+			ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "Go-I",
+		}))
+		require.NoError(t, addToWhitelist(ctx, db, &models.WhitelistEntry{
+			ReviewerLogin: "testuser", EntryType: models.EntryTypeProject, Name: "DO1_Linux",
+		}))
+
+		require.NoError(t, removeFromWhitelist(ctx, db, "testuser", "go  -  i"))
+
+		require.Len(t, db.rows, 1)
+		assert.Equal(t, "DO1_Linux", db.rows[0].Name)
+	})
+
+	t.Run("no matching entry", func(t *testing.T) {
+		db := &fakeWhitelistDatabase{rows: []*models.WhitelistEntry{
+			{ReviewerLogin: "testuser", EntryType: models.EntryTypeProject, Name: "DO1_Linux"},
+		}}
+
+		err := removeFromWhitelist(ctx, db, "testuser", "CPP1_s21_matrix")
+
+		assert.NoError(t, err)
+		assert.Len(t, db.rows, 1)
+	})
+}
+
 // TestParameterBuilding tests parameter construction for different types
 func TestParameterBuilding(t *testing.T) {
 	tests := []struct {
@@ -899,6 +1406,146 @@ func TestStatusValidation(t *testing.T) {
 	}
 }
 
+// TestValidateStatusUpdate tests the transition guard used by UpdateReviewRequestStatusChecked
+func TestValidateStatusUpdate(t *testing.T) {
+	t.Run("approved request cannot be cancelled", func(t *testing.T) {
+		err := validateStatusUpdate("req-1", models.StatusApproved, models.StatusCancelled)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "req-1")
+		assert.Contains(t, err.Error(), models.ErrInvalidTransition)
+	})
+
+	t.Run("waiting request can be approved", func(t *testing.T) {
+		err := validateStatusUpdate("req-2", models.StatusWaitingForApprove, models.StatusApproved)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown project review cannot jump to approved", func(t *testing.T) {
+		err := validateStatusUpdate("req-3", models.StatusUnknownProjectReview, models.StatusApproved)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestUpdateReviewRequestStatusChecked(t *testing.T) {
+	ctx := context.Background()
+	withStatus := func(status string) func(sql string) result.Result {
+		return func(sql string) result.Result {
+			if containsAll(sql, "SELECT status", "FROM review_requests") {
+				return &fakeNamedResult{rows: []map[string]any{{"status": status}}}
+			}
+			return nil
+		}
+	}
+
+	t.Run("approved request cannot be cancelled", func(t *testing.T) {
+		db := &fakeTxDatabase{results: withStatus(models.StatusApproved)}
+
+		err := updateReviewRequestStatusChecked(ctx, db, "req-1", models.StatusCancelled, nil)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), models.ErrInvalidTransition)
+		assert.Len(t, statementsContaining(db.attempted, "SELECT status"), 1)
+		assert.Empty(t, statementsContaining(db.attempted, "UPDATE review_requests"))
+		assert.Empty(t, db.committed)
+	})
+
+	t.Run("allowed transition updates in the same transaction", func(t *testing.T) {
+		db := &fakeTxDatabase{results: withStatus(models.StatusWaitingForApprove)}
+
+		err := updateReviewRequestStatusChecked(ctx, db, "req-1", models.StatusApproved, nil)
+
+		require.NoError(t, err)
+		require.Len(t, db.committed, 2)
+		assert.Contains(t, db.committed[0], "SELECT status")
+		assert.Contains(t, db.committed[1], "UPDATE review_requests")
+	})
+
+	t.Run("missing request", func(t *testing.T) {
+		db := &fakeTxDatabase{results: func(sql string) result.Result { return &fakeNamedResult{} }}
+
+		err := updateReviewRequestStatusChecked(ctx, db, "req-1", models.StatusApproved, nil)
+
+		assert.ErrorContains(t, err, "review request not found")
+		assert.Empty(t, statementsContaining(db.attempted, "UPDATE review_requests"))
+	})
+}
+
+// TestClaimExpiredReview tests that of two claims on the same review only the first wins
+func TestClaimExpiredReview(t *testing.T) {
+	ctx := context.Background()
+	db := &fakeTxDatabase{}
+	// The status row reflects every UPDATE committed so far
+	db.results = func(sql string) result.Result {
+		if !containsAll(sql, "SELECT status", "FROM review_requests") {
+			return nil
+		}
+		status := models.StatusWaitingForApprove
+		if len(statementsContaining(db.committed, "UPDATE review_requests")) > 0 {
+			status = models.StatusCancelled
+		}
+		return &fakeNamedResult{rows: []map[string]any{{"status": status}}}
+	}
+
+	first, err := claimExpiredReview(ctx, db, "req-1", models.StatusWaitingForApprove, models.StatusCancelled)
+	require.NoError(t, err)
+	second, err := claimExpiredReview(ctx, db, "req-1", models.StatusWaitingForApprove, models.StatusCancelled)
+	require.NoError(t, err)
+
+	assert.True(t, first)
+	assert.False(t, second)
+	updates := statementsContaining(db.committed, "UPDATE review_requests")
+	require.Len(t, updates, 1)
+	assert.Contains(t, updates[0], "WHERE id = $id AND status = $expected_status")
+}
+
+// TestClaimReview tests that only the first of two competing claims wins
+func TestClaimReview(t *testing.T) {
+	statuses := map[string]string{"req-1": models.StatusWaitingForApprove}
+
+	claim := func(id string) (bool, error) {
+		read := func() (string, bool, error) {
+			status, ok := statuses[id]
+			return status, ok, nil
+		}
+		write := func() error {
+			statuses[id] = models.StatusAutoCancelled
+			return nil
+		}
+		return claimReview(models.StatusWaitingForApprove, read, write)
+	}
+
+	t.Run("two claims on the same review", func(t *testing.T) {
+		first, err := claim("req-1")
+		require.NoError(t, err)
+		second, err := claim("req-1")
+		require.NoError(t, err)
+
+		assert.True(t, first, "first claim should win")
+		assert.False(t, second, "second claim should lose")
+		assert.Equal(t, models.StatusAutoCancelled, statuses["req-1"])
+	})
+
+	t.Run("missing review is not claimed", func(t *testing.T) {
+		claimed, err := claim("missing")
+
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+	})
+
+	t.Run("read error is returned", func(t *testing.T) {
+		readErr := errors.New("read failed")
+		claimed, err := claimReview(models.StatusWaitingForApprove,
+			func() (string, bool, error) { return "", false, readErr },
+			func() error { return nil })
+
+		assert.ErrorIs(t, err, readErr)
+		assert.False(t, claimed)
+	})
+}
+
 // TestTransactionLogic tests transaction-related logic
 func TestTransactionLogic(t *testing.T) {
 	t.Run("upsert project families in transaction", func(t *testing.T) {
@@ -958,19 +1605,208 @@ func TestWhitelistOperations(t *testing.T) {
 	})
 }
 
-// TestContextHandling tests context handling in various operations
-func TestContextHandling(t *testing.T) {
-	t.Run("context with timeout", func(t *testing.T) {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+// TestWhitelistMatches tests whitelist matching by project and by family membership
+func TestWhitelistMatches(t *testing.T) {
+	entries := []*models.WhitelistEntry{
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "C"},
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeProject, Name: "DO1_Linux"},
+	}
 
-		assert.NotNil(t, ctx)
-		assert.False(t, ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded)
-	})
+	tests := []struct {
+		name        string
+		projectName string
+		familyLabel string
+		expected    bool
+	}{
+		{
+			name:        "whitelisted only via family",
+			projectName: "C2_SimpleBashUtils",
+			familyLabel: "C",
+			expected:    true,
+		},
+		{
+			name:        "whitelisted directly by project",
+			projectName: "DO1_Linux",
+			familyLabel: "DevOps",
+			expected:    true,
+		},
+		{
+			name:        "project without known family",
+			projectName: "CPP1_s21_matrixplus",
+			familyLabel: "",
+			expected:    false,
+		},
+		{
+			name:        "family name does not match project entry",
+			projectName: "C",
+			familyLabel: "Other",
+			expected:    false,
+		},
+		{
+			name:        "project matches ignoring case",
+			projectName: "do1_linux",
+			familyLabel: "",
+			expected:    true,
+		},
+		{
+			name:        "family matches ignoring spacing and case",
+			projectName: "GO1_Boot",
+			familyLabel: "go-i",
+			expected:    true,
+		},
+	}
 
-	t.Run("cancelled context", func(t *testing.T) {
-		ctx, cancel := context.WithCancel(context.Background())
-		cancel()
+	entries = append(entries, &models.WhitelistEntry{ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "Go - I"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, whitelistMatches(entries, tt.projectName, tt.familyLabel))
+		})
+	}
+
+	t.Run("empty whitelist", func(t *testing.T) {
+		assert.False(t, whitelistMatches(nil, "C2_SimpleBashUtils", "C"))
+	})
+}
+
+// TestValidateWhitelistEntries tests the checks run before ReplaceUserWhitelist writes
+func TestValidateWhitelistEntries(t *testing.T) {
+	tests := []struct {
+		name          string
+		reviewerLogin string
+		entries       []*models.WhitelistEntry
+		expectError   bool
+	}{
+		{
+			name:          "valid entries",
+			reviewerLogin: "testuser",
+			entries: []*models.WhitelistEntry{
+				{ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "C"},
+				{EntryType: models.EntryTypeProject, Name: "DO1_Linux"},
+			},
+		},
+		{
+			name:          "empty set clears whitelist",
+			reviewerLogin: "testuser",
+			entries:       []*models.WhitelistEntry{},
+		},
+		{
+			name:          "invalid entry type",
+			reviewerLogin: "testuser",
+			entries: []*models.WhitelistEntry{
+				{EntryType: "GROUP", Name: "C"},
+			},
+			expectError: true,
+		},
+		{
+			name:          "entry for another reviewer",
+			reviewerLogin: "testuser",
+			entries: []*models.WhitelistEntry{
+				{ReviewerLogin: "otheruser", EntryType: models.EntryTypeFamily, Name: "C"},
+			},
+			expectError: true,
+		},
+		{
+			name:          "nil entry",
+			reviewerLogin: "testuser",
+			entries:       []*models.WhitelistEntry{nil},
+			expectError:   true,
+		},
+		{
+			name:          "empty reviewer login",
+			reviewerLogin: "",
+			entries:       []*models.WhitelistEntry{},
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := validateWhitelistEntries(tt.reviewerLogin, tt.entries)
+			if tt.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, normalized, len(tt.entries))
+			}
+		})
+	}
+
+	t.Run("normalizes copies without touching the input", func(t *testing.T) {
+		input := &models.WhitelistEntry{EntryType: " family ", Name: "  go-i "}
+
+		normalized, err := validateWhitelistEntries("testuser", []*models.WhitelistEntry{input})
+
+		require.NoError(t, err)
+		assert.Equal(t, models.EntryTypeFamily, normalized[0].EntryType)
+		assert.Equal(t, "go - i", normalized[0].Name)
+		assert.Equal(t, " family ", input.EntryType)
+		assert.Equal(t, "  go-i ", input.Name)
+	})
+}
+
+func TestReplaceUserWhitelist(t *testing.T) {
+	ctx := context.Background()
+	smaller := []*models.WhitelistEntry{
+		{EntryType: models.EntryTypeFamily, Name: "C"},
+		{EntryType: models.EntryTypeProject, Name: "DO1_Linux"},
+	}
+
+	t.Run("replacing with a smaller set removes stale entries atomically", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+
+		err := replaceUserWhitelist(ctx, db, "testuser", smaller)
+
+		require.NoError(t, err)
+		require.Len(t, db.committed, 3)
+		assert.Contains(t, db.committed[0], "DELETE FROM user_project_whitelist")
+		assert.Contains(t, db.committed[1], "UPSERT INTO user_project_whitelist")
+		assert.Contains(t, db.committed[2], "UPSERT INTO user_project_whitelist")
+
+		var names []string
+		for _, params := range db.committedParams[1:] {
+			params.Each(func(name string, v types.Value) {
+				if name == "$name" {
+					names = append(names, v.Yql())
+				}
+			})
+		}
+		assert.Equal(t, []string{`"C"u`, `"DO1_Linux"u`}, names)
+	})
+
+	t.Run("insert failure rolls back the delete", func(t *testing.T) {
+		db := &fakeTxDatabase{failOn: "UPSERT INTO user_project_whitelist"}
+
+		err := replaceUserWhitelist(ctx, db, "testuser", smaller)
+
+		require.Error(t, err)
+		assert.Len(t, statementsContaining(db.attempted, "DELETE FROM user_project_whitelist"), 1)
+		assert.Empty(t, db.committed)
+	})
+
+	t.Run("invalid entry writes nothing", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+
+		err := replaceUserWhitelist(ctx, db, "testuser", []*models.WhitelistEntry{{EntryType: "GROUP", Name: "C"}})
+
+		assert.Error(t, err)
+		assert.Empty(t, db.attempted)
+	})
+}
+
+// TestContextHandling tests context handling in various operations
+func TestContextHandling(t *testing.T) {
+	t.Run("context with timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		assert.NotNil(t, ctx)
+		assert.False(t, ctx.Err() != nil && ctx.Err() == context.DeadlineExceeded)
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
 
 		assert.Error(t, ctx.Err())
 		assert.Equal(t, context.Canceled, ctx.Err())
@@ -1012,3 +1848,1324 @@ func BenchmarkTablePathPrefix(b *testing.B) {
 		_ = TablePathPrefix(path)
 	}
 }
+
+func TestDedupeWhitelistEntries(t *testing.T) {
+	entries := []*models.WhitelistEntry{
+		{EntryType: models.EntryTypeFamily, Name: "Go - I"},
+		{EntryType: models.EntryTypeFamily, Name: "  go-i "},
+		{EntryType: models.EntryTypeProject, Name: "Go - I"},
+		{EntryType: models.EntryTypeFamily, Name: "C"},
+	}
+
+	normalized, err := validateWhitelistEntries("testuser", entries)
+	require.NoError(t, err)
+	unique := dedupeWhitelistEntries(normalized)
+
+	require.Len(t, unique, 3)
+	assert.Equal(t, "Go - I", unique[0].Name)
+	assert.Equal(t, models.EntryTypeProject, unique[1].EntryType)
+	assert.Equal(t, "C", unique[2].Name)
+
+	found := findWhitelistEntry(unique, &models.WhitelistEntry{EntryType: models.EntryTypeFamily, Name: "GO -I"})
+	require.NotNil(t, found)
+	assert.Equal(t, "Go - I", found.Name)
+	assert.Nil(t, findWhitelistEntry(unique, &models.WhitelistEntry{EntryType: models.EntryTypeFamily, Name: "Rust"}))
+}
+
+func TestGetUsersByTelegramChatIDs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("empty ids skip the query", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		users, err := getUsersByTelegramChatIDs(ctx, mockDB, nil)
+
+		require.NoError(t, err)
+		assert.Empty(t, users)
+		mockDB.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wraps query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "List<Int64>", "telegram_chat_id IN $telegram_chat_ids")
+		}), mock.Anything).Return(nil, queryErr).Once()
+
+		users, err := getUsersByTelegramChatIDs(ctx, mockDB, []int64{1, 2})
+
+		assert.Nil(t, users)
+		assert.ErrorIs(t, err, queryErr)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("mix of existing and missing chat ids", func(t *testing.T) {
+		requested := uniqueChatIDs([]int64{100, 200, 300, 100})
+		found := []*models.User{
+			{ReviewerLogin: "alice", TelegramChatID: 100},
+			{ReviewerLogin: "carol", TelegramChatID: 300},
+			{ReviewerLogin: "stray", TelegramChatID: 999},
+		}
+
+		users := indexUsersByChatID(found, requested)
+
+		assert.Equal(t, []int64{100, 200, 300}, requested)
+		require.Len(t, users, 2)
+		assert.Equal(t, "alice", users[100].ReviewerLogin)
+		assert.Equal(t, "carol", users[300].ReviewerLogin)
+		_, ok := users[200]
+		assert.False(t, ok)
+	})
+}
+
+func TestArchiveReviewRequestsBefore(t *testing.T) {
+	ctx := context.Background()
+	cutoff := uint32(1700000000)
+
+	t.Run("only final-status rows older than cutoff are archivable", func(t *testing.T) {
+		candidates := []archiveCandidate{
+			{ID: "old-approved", Status: models.StatusApproved, CreatedAt: cutoff - 100},
+			{ID: "old-cancelled", Status: models.StatusAutoCancelledNotWhitelisted, CreatedAt: cutoff - 1},
+			{ID: "old-pending", Status: models.StatusWaitingForApprove, CreatedAt: cutoff - 100},
+			{ID: "new-approved", Status: models.StatusApproved, CreatedAt: cutoff + 100},
+			{ID: "at-cutoff", Status: models.StatusCancelled, CreatedAt: cutoff},
+		}
+
+		ids := archivableIDs(candidates, cutoff)
+
+		assert.Equal(t, []string{"old-approved", "old-cancelled"}, ids)
+	})
+
+	t.Run("non-positive cutoff archives nothing", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		n, err := archiveReviewRequestsBefore(ctx, mockDB, 0, 10)
+
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		mockDB.AssertNotCalled(t, "DoTx", mock.Anything, mock.Anything)
+	})
+
+	t.Run("transaction error is returned", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		txErr := errors.New("tx failed")
+		mockDB.On("DoTx", ctx, mock.Anything).Return(txErr).Once()
+
+		n, err := archiveReviewRequestsBefore(ctx, mockDB, int64(cutoff), 10)
+
+		assert.ErrorIs(t, err, txErr)
+		assert.Zero(t, n)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("stops after a short batch", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("DoTx", ctx, mock.Anything).Return(nil).Once()
+
+		n, err := archiveReviewRequestsBefore(ctx, mockDB, int64(cutoff), 10)
+
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("final statuses are all final", func(t *testing.T) {
+		for _, status := range models.FinalStatuses() {
+			assert.True(t, models.IsFinalStatus(status), status)
+		}
+	})
+}
+
+// fakeRowsResult is a result.Result that iterates over in-memory rows
+type fakeRowsResult struct {
+	result.Result
+	rows []string
+	pos  int
+}
+
+func (r *fakeRowsResult) NextRow() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *fakeRowsResult) current() string {
+	return r.rows[r.pos-1]
+}
+
+func (r *fakeRowsResult) Close() error {
+	return nil
+}
+
+func (r *fakeRowsResult) Err() error {
+	return nil
+}
+
+func (r *fakeRowsResult) NextResultSetErr(ctx context.Context, columns ...string) error {
+	return nil
+}
+
+// ScanNamed writes the current row into every *string destination
+func (r *fakeRowsResult) ScanNamed(values ...named.Value) error {
+	for _, v := range values {
+		dst, ok := v.Value.(*string)
+		if !ok {
+			return fmt.Errorf("unsupported destination for column %s", v.Name)
+		}
+		*dst = r.current()
+	}
+	return nil
+}
+
+func TestQueryPaged(t *testing.T) {
+	ctx := context.Background()
+	baseSQL := `SELECT reviewer_login FROM users WHERE status = "ACTIVE";`
+	scan := func(res result.Result) (string, error) {
+		return res.(*fakeRowsResult).current(), nil
+	}
+	key := func(login string) string { return login }
+
+	t.Run("two pages", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		isPagedSQL := mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "FROM users", "WHERE reviewer_login > $cursor", "ORDER BY reviewer_login", "LIMIT $limit")
+		})
+		mockDB.On("Query", ctx, isPagedSQL, mock.Anything).
+			Return(&fakeRowsResult{rows: []string{"alice", "bob"}}, nil).Once()
+		mockDB.On("Query", ctx, isPagedSQL, mock.Anything).
+			Return(&fakeRowsResult{rows: []string{"carol"}}, nil).Once()
+
+		first, cursor, err := queryPaged(ctx, mockDB, baseSQL, "reviewer_login", scan, key, 2, "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice", "bob"}, first)
+		assert.Equal(t, "bob", cursor)
+
+		second, cursor, err := queryPaged(ctx, mockDB, baseSQL, "reviewer_login", scan, key, 2, cursor)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"carol"}, second)
+		assert.Empty(t, cursor)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("scan error stops the page", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		scanErr := errors.New("scan failed")
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).
+			Return(&fakeRowsResult{rows: []string{"alice"}}, nil).Once()
+
+		items, cursor, err := queryPaged(ctx, mockDB, baseSQL, "reviewer_login",
+			func(result.Result) (string, error) { return "", scanErr }, key, 2, "")
+
+		assert.ErrorIs(t, err, scanErr)
+		assert.Nil(t, items)
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("cursor is bound as Utf8", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		var gotSQL string
+		var gotParams []table.ParameterOption
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				gotSQL = args.String(1)
+				gotParams = args.Get(2).([]table.ParameterOption)
+			}).
+			Return(&fakeRowsResult{}, nil).Once()
+
+		_, _, err := queryPaged(ctx, mockDB, baseSQL, "reviewer_login", scan, key, 2, "bob")
+
+		require.NoError(t, err)
+		assert.Contains(t, gotSQL, "DECLARE $cursor AS Utf8;")
+		assert.Equal(t, `"bob"u`, paramValue(gotParams, "$cursor"))
+	})
+
+	t.Run("non-positive limit", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		_, _, err := queryPaged(ctx, mockDB, baseSQL, "reviewer_login", scan, key, 0, "")
+
+		assert.Error(t, err)
+		mockDB.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetFamilyLabelsForProjects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("empty names skip the query", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		labels, err := getFamilyLabelsForProjects(ctx, mockDB, []string{"", ""})
+
+		require.NoError(t, err)
+		assert.Empty(t, labels)
+		mockDB.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wraps query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "List<Utf8>", "project_name IN $project_names")
+		}), mock.Anything).Return(nil, queryErr).Once()
+
+		labels, err := getFamilyLabelsForProjects(ctx, mockDB, []string{"C2_SimpleBashUtils"})
+
+		assert.Nil(t, labels)
+		assert.ErrorIs(t, err, queryErr)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("unknown projects are omitted", func(t *testing.T) {
+		requested := uniqueStrings([]string{"C2_SimpleBashUtils", "DO1_Linux", "Unknown_Project", "DO1_Linux"})
+		found := []*models.ProjectFamily{
+			{FamilyLabel: "C", ProjectName: "C2_SimpleBashUtils"},
+			{FamilyLabel: "DevOps", ProjectName: "DO1_Linux"},
+		}
+
+		labels := familyLabelsByProject(found, requested)
+
+		assert.Equal(t, []string{"C2_SimpleBashUtils", "DO1_Linux", "Unknown_Project"}, requested)
+		assert.Equal(t, map[string]string{"C2_SimpleBashUtils": "C", "DO1_Linux": "DevOps"}, labels)
+		_, ok := labels["Unknown_Project"]
+		assert.False(t, ok)
+	})
+}
+
+func TestRecordAuthTimestamps(t *testing.T) {
+	ctx := context.Background()
+	at := int64(1705309200)
+
+	t.Run("success updates only last_auth_success_at", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "UPDATE users", "SET last_auth_success_at = $at") &&
+				!containsAll(sql, "last_auth_failure_at") && !containsAll(sql, "status")
+		}), mock.Anything).Return(nil).Once()
+
+		err := recordAuthTimestamp(ctx, mockDB, recordAuthSuccessSQL, "testuser", at)
+
+		assert.NoError(t, err)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("failure updates only last_auth_failure_at", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "UPDATE users", "SET last_auth_failure_at = $at") &&
+				!containsAll(sql, "last_auth_success_at") && !containsAll(sql, "status")
+		}), mock.Anything).Return(nil).Once()
+
+		err := recordAuthTimestamp(ctx, mockDB, recordAuthFailureSQL, "testuser", at)
+
+		assert.NoError(t, err)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("propagates exec error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		execErr := errors.New("exec failed")
+		mockDB.On("Exec", ctx, mock.Anything, mock.Anything).Return(execErr).Once()
+
+		err := recordAuthTimestamp(ctx, mockDB, recordAuthFailureSQL, "testuser", at)
+
+		assert.ErrorIs(t, err, execErr)
+	})
+
+	t.Run("rejects invalid input without executing", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		assert.Error(t, recordAuthTimestamp(ctx, mockDB, recordAuthSuccessSQL, "", at))
+		assert.Error(t, recordAuthTimestamp(ctx, mockDB, recordAuthSuccessSQL, "testuser", -1))
+		mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeactivateStaleUsers(t *testing.T) {
+	ctx := context.Background()
+	cutoff := int64(1705309200)
+	ts := func(v int64) *uint32 {
+		u := uint32(v)
+		return &u
+	}
+
+	t.Run("only the user who keeps failing is selected", func(t *testing.T) {
+		users := []*models.User{
+			{ReviewerLogin: "recovered", Status: models.UserStatusActive, LastAuthSuccessAt: ts(cutoff + 120), LastAuthFailureAt: ts(cutoff - 60)},
+			{ReviewerLogin: "failing", Status: models.UserStatusActive, LastAuthSuccessAt: ts(cutoff - 3600), LastAuthFailureAt: ts(cutoff + 60)},
+		}
+
+		assert.Equal(t, []string{"failing"}, staleUserLogins(users, cutoff))
+	})
+
+	t.Run("transaction error is returned", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		txErr := errors.New("tx failed")
+		mockDB.On("DoTx", ctx, mock.Anything).Return(txErr).Once()
+
+		n, err := deactivateStaleUsers(ctx, mockDB, cutoff)
+
+		assert.ErrorIs(t, err, txErr)
+		assert.Zero(t, n)
+		mockDB.AssertExpectations(t)
+	})
+}
+
+// fakeTx records statements executed in a transaction
+type fakeTx struct {
+	table.TransactionActor
+	executed []string
+	params   []*table.QueryParameters
+	failOn   string
+	results  func(sql string) result.Result
+}
+
+func (tx *fakeTx) Execute(ctx context.Context, sql string, params *table.QueryParameters, opts ...options.ExecuteDataQueryOption) (result.Result, error) {
+	if tx.failOn != "" && containsAll(sql, tx.failOn) {
+		return nil, errors.New("execute failed")
+	}
+	tx.executed = append(tx.executed, sql)
+	tx.params = append(tx.params, params)
+	if tx.results != nil {
+		if res := tx.results(sql); res != nil {
+			return res, nil
+		}
+	}
+	return &fakeRowsResult{}, nil
+}
+
+// fakeTxDatabase commits a transaction's statements only when fn succeeds
+// results, when set, supplies the rows returned for a statement inside the transaction
+type fakeTxDatabase struct {
+	MockDatabase
+	failOn          string
+	results         func(sql string) result.Result
+	attempted       []string
+	committed       []string
+	committedParams []*table.QueryParameters
+}
+
+func (d *fakeTxDatabase) DoTx(ctx context.Context, fn func(ctx context.Context, tx table.TransactionActor) error) error {
+	tx := &fakeTx{failOn: d.failOn, results: d.results}
+	err := fn(ctx, tx)
+	d.attempted = append(d.attempted, tx.executed...)
+	if err != nil {
+		return err
+	}
+	d.committed = append(d.committed, tx.executed...)
+	d.committedParams = append(d.committedParams, tx.params...)
+	return nil
+}
+
+// statementsContaining returns the statements that contain every fragment
+func statementsContaining(statements []string, fragments ...string) []string {
+	var matched []string
+	for _, sql := range statements {
+		if containsAll(sql, fragments...) {
+			matched = append(matched, sql)
+		}
+	}
+	return matched
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	composed := func(txCtx context.Context) error {
+		if err := UpdateUserStatus(txCtx, "testuser", models.UserStatusInactive); err != nil {
+			return err
+		}
+		return updateReviewRequestStatus(txCtx, NewDatabaseAdapter(), "req-1", models.StatusCancelled, nil)
+	}
+
+	t.Run("writes commit together", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+
+		err := withTx(ctx, db, composed)
+
+		require.NoError(t, err)
+		require.Len(t, db.committed, 2)
+		assert.Contains(t, db.committed[0], "UPDATE users")
+		assert.Contains(t, db.committed[1], "UPDATE review_requests")
+	})
+
+	t.Run("writes roll back together on error", func(t *testing.T) {
+		db := &fakeTxDatabase{failOn: "UPDATE review_requests"}
+
+		err := withTx(ctx, db, composed)
+
+		assert.Error(t, err)
+		assert.Empty(t, db.committed)
+	})
+
+	t.Run("nested DoTx joins the outer transaction", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+
+		err := withTx(ctx, db, func(txCtx context.Context) error {
+			return DoTx(txCtx, func(ctx context.Context, tx table.TransactionActor) error {
+				return Exec(ctx, "UPSERT INTO users (reviewer_login) VALUES ($reviewer_login);")
+			})
+		})
+
+		require.NoError(t, err)
+		assert.Len(t, db.committed, 1)
+	})
+}
+
+// paramValue returns the YQL literal of the named parameter, or "" if absent
+func paramValue(params []table.ParameterOption, name string) string {
+	for _, p := range params {
+		if p.Name() == name {
+			return p.Value().Yql()
+		}
+	}
+	return ""
+}
+
+func TestGetRecentReviewRequests(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("orders newest first and limits rows", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "WHERE reviewer_login = $reviewer_login", "ORDER BY created_at DESC", "LIMIT $limit")
+		}), mock.MatchedBy(func(params []table.ParameterOption) bool {
+			return paramValue(params, "$limit") == types.Uint64Value(5).Yql() &&
+				paramValue(params, "$reviewer_login") == types.TextValue("testuser").Yql()
+		})).Return(nil, queryErr).Once()
+
+		requests, err := getRecentReviewRequests(ctx, mockDB, "testuser", 5)
+
+		assert.Nil(t, requests)
+		assert.ErrorIs(t, err, queryErr)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("rejects non-positive limit", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		for _, limit := range []int{0, -1} {
+			_, err := getRecentReviewRequests(ctx, mockDB, "testuser", limit)
+			assert.Error(t, err)
+		}
+		mockDB.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestConnectWithRetry(t *testing.T) {
+	t.Run("succeeds on third attempt", func(t *testing.T) {
+		calls := 0
+		driver, err := connectWithRetry(context.Background(), 4, time.Millisecond, func(ctx context.Context) (*ydb.Driver, error) {
+			calls++
+			if calls < 3 {
+				return nil, errors.New("unavailable")
+			}
+			return &ydb.Driver{}, nil
+		})
+
+		require.NoError(t, err)
+		assert.NotNil(t, driver)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("returns last error when exhausted", func(t *testing.T) {
+		calls := 0
+		driver, err := connectWithRetry(context.Background(), 3, time.Millisecond, func(ctx context.Context) (*ydb.Driver, error) {
+			calls++
+			return nil, fmt.Errorf("attempt %d failed", calls)
+		})
+
+		assert.Nil(t, driver)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "attempt 3 failed")
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("stops when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		driver, err := connectWithRetry(ctx, 5, time.Hour, func(ctx context.Context) (*ydb.Driver, error) {
+			calls++
+			cancel()
+			return nil, errors.New("unavailable")
+		})
+
+		assert.Nil(t, driver)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestListReviewerLogins(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("returns only logins of seeded users", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "SELECT reviewer_login", "FROM users") && !containsAll(sql, "telegram_chat_id")
+		}), mock.Anything).Return(&fakeRowsResult{rows: []string{"alice", "bob", "carol"}}, nil).Once()
+
+		logins, err := listReviewerLogins(ctx, mockDB)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice", "bob", "carol"}, logins)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("filters by status", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "WHERE status IN $statuses")
+		}), mock.MatchedBy(func(params []table.ParameterOption) bool {
+			return paramValue(params, "$statuses") == types.ListValue(types.TextValue(models.UserStatusActive)).Yql()
+		})).Return(&fakeRowsResult{rows: []string{"alice"}}, nil).Once()
+
+		logins, err := listReviewerLogins(ctx, mockDB, models.UserStatusActive)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, logins)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("wraps query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).Return(nil, queryErr).Once()
+
+		logins, err := listReviewerLogins(ctx, mockDB)
+
+		assert.Nil(t, logins)
+		assert.ErrorIs(t, err, queryErr)
+	})
+}
+
+func TestSetMinutesSetting(t *testing.T) {
+	ctx := context.Background()
+	fields := []string{
+		"response_deadline_shift_minutes",
+		"non_whitelist_cancel_delay_minutes",
+		"slot_shift_threshold_minutes",
+		"slot_shift_duration_minutes",
+		"cleanup_durations_minutes",
+	}
+
+	for _, field := range fields {
+		t.Run(field, func(t *testing.T) {
+			for _, minutes := range []int32{models.MinSettingMinutes, 30, models.MaxSettingMinutes} {
+				mockDB := NewMockDatabase()
+				mockDB.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+					return containsAll(sql, "UPDATE user_settings", "SET "+field+" = $value", "DECLARE $value AS Int32")
+				}), mock.Anything).Return(nil).Once()
+
+				err := setMinutesSetting(ctx, mockDB, "testuser", field, minutes)
+
+				assert.NoError(t, err)
+				mockDB.AssertExpectations(t)
+			}
+
+			for _, minutes := range []int32{-5, models.MaxSettingMinutes + 1, 100000} {
+				mockDB := NewMockDatabase()
+
+				err := setMinutesSetting(ctx, mockDB, "testuser", field, minutes)
+
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), field)
+				assert.Contains(t, err.Error(), "between 0 and 240")
+				mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+
+	t.Run("requires reviewer login", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		err := setMinutesSetting(ctx, mockDB, "", "slot_shift_threshold_minutes", 10)
+
+		assert.Error(t, err)
+		mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// fakeNamedResult serves rows of column values to ScanNamed
+type fakeNamedResult struct {
+	result.Result
+	rows []map[string]any
+	pos  int
+}
+
+func (r *fakeNamedResult) NextRow() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *fakeNamedResult) Close() error {
+	return nil
+}
+
+func (r *fakeNamedResult) Err() error {
+	return nil
+}
+
+func (r *fakeNamedResult) NextResultSetErr(ctx context.Context, columns ...string) error {
+	return nil
+}
+
+// CurrentResultSet exposes the current row's columns so yscan can map them to struct fields
+func (r *fakeNamedResult) CurrentResultSet() result.Set {
+	var columns []string
+	if r.pos > 0 {
+		for name := range r.rows[r.pos-1] {
+			columns = append(columns, name)
+		}
+		sort.Strings(columns)
+	}
+	return fakeResultSet{columns: columns}
+}
+
+// fakeResultSet describes the columns of a fakeNamedResult row
+type fakeResultSet struct {
+	result.Set
+	columns []string
+}
+
+func (s fakeResultSet) ColumnCount() int {
+	return len(s.columns)
+}
+
+func (s fakeResultSet) Columns(it func(options.Column)) {
+	for _, name := range s.columns {
+		it(options.Column{Name: name})
+	}
+}
+
+// ScanNamed copies each named column of the current row into its destination
+func (r *fakeNamedResult) ScanNamed(values ...named.Value) error {
+	row := r.rows[r.pos-1]
+	for _, v := range values {
+		col, ok := row[v.Name]
+		if !ok {
+			return fmt.Errorf("unknown column %s", v.Name)
+		}
+		reflect.ValueOf(v.Value).Elem().Set(reflect.ValueOf(col))
+	}
+	return nil
+}
+
+func TestGetUserSettingsForUsers(t *testing.T) {
+	ctx := context.Background()
+	stored := func(login string, deadlineShift int32) map[string]any {
+		return map[string]any{
+			"reviewer_login":                     login,
+			"response_deadline_shift_minutes":    deadlineShift,
+			"non_whitelist_cancel_delay_minutes": int32(3),
+			"notify_whitelist_timeout":           true,
+			"notify_non_whitelist_cancel":        true,
+			"slot_shift_threshold_minutes":       int32(25),
+			"slot_shift_duration_minutes":        int32(15),
+			"cleanup_durations_minutes":          int32(15),
+		}
+	}
+	isBulkSelect := mock.MatchedBy(func(sql string) bool {
+		return containsAll(sql, "FROM user_settings", "WHERE reviewer_login IN $reviewer_logins")
+	})
+	hasLogins := mock.MatchedBy(func(params []table.ParameterOption) bool {
+		return paramValue(params, "$reviewer_logins") == `["alice"u,"bob"u,"carol"u]`
+	})
+
+	t.Run("fills defaults for users without settings", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, isBulkSelect, hasLogins).Return(&fakeNamedResult{rows: []map[string]any{
+			stored("alice", 45), stored("carol", 90),
+		}}, nil).Once()
+
+		settings, err := getUserSettingsForUsers(ctx, mockDB, []string{"alice", "bob", "alice", "carol"}, true)
+
+		require.NoError(t, err)
+		require.Len(t, settings, 3)
+		assert.Equal(t, int32(45), settings["alice"].ResponseDeadlineShiftMinutes)
+		assert.Equal(t, int32(90), settings["carol"].ResponseDeadlineShiftMinutes)
+		assert.Equal(t, models.DefaultUserSettings("bob"), settings["bob"])
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("omits users without settings", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, isBulkSelect, hasLogins).Return(&fakeNamedResult{rows: []map[string]any{
+			stored("alice", 45), stored("carol", 90),
+		}}, nil).Once()
+
+		settings, err := getUserSettingsForUsers(ctx, mockDB, []string{"alice", "bob", "carol"}, false)
+
+		require.NoError(t, err)
+		assert.Len(t, settings, 2)
+		assert.NotContains(t, settings, "bob")
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("no logins skips the query", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		settings, err := getUserSettingsForUsers(ctx, mockDB, nil, true)
+
+		require.NoError(t, err)
+		assert.Empty(t, settings)
+		mockDB.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, isBulkSelect, mock.Anything).Return(nil, errors.New("connection lost")).Once()
+
+		_, err := getUserSettingsForUsers(ctx, mockDB, []string{"alice"}, true)
+
+		assert.ErrorContains(t, err, "connection lost")
+	})
+}
+
+func TestReviewRequestsBySlot(t *testing.T) {
+	ctx := context.Background()
+	row := func(id string, createdAt uint32) map[string]any {
+		return map[string]any{
+			"id":               id,
+			"reviewer_login":   "testuser",
+			"calendar_slot_id": "slot-1",
+			"status":           models.StatusApproved,
+			"created_at":       createdAt,
+		}
+	}
+	older := row("req-old", 1700000000)
+	latest := row("req-new", 1700003600)
+	isSlotParam := mock.MatchedBy(func(params []table.ParameterOption) bool {
+		return paramValue(params, "$calendar_slot_id") == `"slot-1"u`
+	})
+
+	t.Run("latest request is returned", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "WHERE calendar_slot_id = $calendar_slot_id", "ORDER BY created_at DESC", "LIMIT 1")
+		}), isSlotParam).Return(&fakeNamedResult{rows: []map[string]any{latest}}, nil).Once()
+
+		req, err := getReviewRequestByCalendarSlotID(ctx, mockDB, "slot-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "req-new", req.ID)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("history returns every request newest first", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "WHERE calendar_slot_id = $calendar_slot_id", "ORDER BY created_at DESC") &&
+				!strings.Contains(sql, "LIMIT")
+		}), isSlotParam).Return(&fakeNamedResult{rows: []map[string]any{latest, older}}, nil).Once()
+
+		history, err := getReviewRequestHistoryBySlot(ctx, mockDB, "slot-1")
+
+		require.NoError(t, err)
+		require.Len(t, history, 2)
+		assert.Equal(t, "req-new", history[0].ID)
+		assert.Equal(t, "req-old", history[1].ID)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("missing slot", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.Anything, isSlotParam).Return(&fakeNamedResult{}, nil).Once()
+
+		_, err := getReviewRequestByCalendarSlotID(ctx, mockDB, "slot-1")
+
+		assert.ErrorIs(t, err, ErrReviewRequestNotFound)
+	})
+}
+
+func TestCreateReviewRequestIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	isSlotLookup := mock.MatchedBy(func(sql string) bool {
+		return containsAll(sql, "FROM review_requests", "WHERE calendar_slot_id = $calendar_slot_id")
+	})
+	isInsert := mock.MatchedBy(func(sql string) bool {
+		return containsAll(sql, "INSERT INTO review_requests")
+	})
+
+	t.Run("two calls for the same slot create one row", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+		first := models.NewReviewRequest("testuser", "slot-1", time.Now().Add(time.Hour).Unix())
+		second := models.NewReviewRequest("testuser", "slot-1", time.Now().Add(time.Hour).Unix())
+
+		db.On("Query", mock.Anything, isSlotLookup, mock.Anything).Return(&fakeNamedResult{}, nil).Once()
+		db.On("Query", mock.Anything, isSlotLookup, mock.Anything).Return(&fakeNamedResult{rows: []map[string]any{{
+			"id":                first.ID,
+			"reviewer_login":    first.ReviewerLogin,
+			"calendar_slot_id":  first.CalendarSlotID,
+			"review_start_time": first.ReviewStartTime,
+			"status":            first.Status,
+			"created_at":        first.CreatedAt,
+		}}}, nil).Once()
+		db.On("Exec", mock.Anything, isInsert, mock.Anything).Return(nil).Once()
+
+		stored, created, err := createReviewRequestIfAbsent(ctx, db, first)
+		require.NoError(t, err)
+		assert.True(t, created)
+		assert.Same(t, first, stored)
+
+		stored, created, err = createReviewRequestIfAbsent(ctx, db, second)
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Equal(t, first.ID, stored.ID)
+		assert.Equal(t, "slot-1", stored.CalendarSlotID)
+
+		db.AssertExpectations(t)
+		db.AssertNumberOfCalls(t, "Exec", 1)
+	})
+
+	t.Run("lookup error skips the insert", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+		db.On("Query", mock.Anything, isSlotLookup, mock.Anything).Return(nil, errors.New("connection lost")).Once()
+
+		_, created, err := createReviewRequestIfAbsent(ctx, db, models.NewReviewRequest("testuser", "slot-1", 0))
+
+		assert.ErrorContains(t, err, "connection lost")
+		assert.False(t, created)
+		db.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetUserSettingsOrDefault(t *testing.T) {
+	ctx := context.Background()
+	isSettingsSelect := mock.MatchedBy(func(sql string) bool {
+		return containsAll(sql, "FROM user_settings", "WHERE reviewer_login = $reviewer_login")
+	})
+
+	t.Run("returns existing settings without writing", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+		db.On("Query", mock.Anything, isSettingsSelect, mock.Anything).Return(&fakeNamedResult{rows: []map[string]any{{
+			"reviewer_login":                     "testuser",
+			"response_deadline_shift_minutes":    int32(45),
+			"non_whitelist_cancel_delay_minutes": int32(3),
+			"notify_whitelist_timeout":           false,
+			"notify_non_whitelist_cancel":        true,
+			"slot_shift_threshold_minutes":       int32(30),
+			"slot_shift_duration_minutes":        int32(10),
+			"cleanup_durations_minutes":          int32(20),
+		}}}, nil).Once()
+
+		settings, err := getUserSettingsOrDefault(ctx, db, "testuser")
+
+		require.NoError(t, err)
+		assert.Equal(t, "testuser", settings.ReviewerLogin)
+		assert.Equal(t, int32(45), settings.ResponseDeadlineShiftMinutes)
+		assert.False(t, settings.NotifyWhitelistTimeout)
+		assert.Equal(t, int32(20), settings.CleanupDurationsMinutes)
+		db.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("creates defaults on miss", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+		db.On("Query", mock.Anything, isSettingsSelect, mock.Anything).Return(&fakeNamedResult{}, nil).Once()
+		db.On("Exec", mock.Anything, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "UPSERT INTO user_settings")
+		}), mock.MatchedBy(func(params []table.ParameterOption) bool {
+			return paramValue(params, "$reviewer_login") == `"testuser"u`
+		})).Return(nil).Once()
+
+		settings, err := getUserSettingsOrDefault(ctx, db, "testuser")
+
+		require.NoError(t, err)
+		assert.Equal(t, models.DefaultUserSettings("testuser"), settings)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("propagates query errors without creating", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+		queryErr := errors.New("query failed")
+		db.On("Query", mock.Anything, isSettingsSelect, mock.Anything).Return(nil, queryErr).Once()
+
+		settings, err := getUserSettingsOrDefault(ctx, db, "testuser")
+
+		assert.ErrorIs(t, err, queryErr)
+		assert.Nil(t, settings)
+		db.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteReviewRequest(t *testing.T) {
+	ctx := context.Background()
+	isDelete := mock.MatchedBy(func(sql string) bool {
+		return containsAll(sql, "DELETE FROM review_requests", "WHERE id = $id")
+	})
+
+	for _, id := range []string{"existing-id", "missing-id"} {
+		t.Run("deletes "+id, func(t *testing.T) {
+			mockDB := NewMockDatabase()
+			mockDB.On("Exec", ctx, isDelete, mock.MatchedBy(func(params []table.ParameterOption) bool {
+				return paramValue(params, "$id") == types.TextValue(id).Yql()
+			})).Return(nil).Once()
+
+			err := deleteReviewRequest(ctx, mockDB, id)
+
+			assert.NoError(t, err)
+			mockDB.AssertExpectations(t)
+		})
+	}
+
+	t.Run("propagates exec error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		execErr := errors.New("exec failed")
+		mockDB.On("Exec", ctx, isDelete, mock.Anything).Return(execErr).Once()
+
+		err := deleteReviewRequest(ctx, mockDB, "existing-id")
+
+		assert.ErrorIs(t, err, execErr)
+	})
+
+	t.Run("rejects empty id", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+
+		err := deleteReviewRequest(ctx, mockDB, "")
+
+		assert.Error(t, err)
+		mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetAllReviewRequestsByReviewer(t *testing.T) {
+	ctx := context.Background()
+	isHistory := mock.MatchedBy(func(sql string) bool {
+		return containsAll(sql, "WHERE reviewer_login = $reviewer_login", "ORDER BY created_at;") &&
+			!containsAll(sql, "LIMIT") && !containsAll(sql, "status IN")
+	})
+
+	t.Run("queries full history ordered by created_at", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, isHistory, mock.MatchedBy(func(params []table.ParameterOption) bool {
+			return paramValue(params, "$reviewer_login") == types.TextValue("testuser").Yql()
+		})).Return(&fakeRowsResult{}, nil).Once()
+
+		requests, err := getAllReviewRequestsByReviewer(ctx, mockDB, "testuser")
+
+		assert.NoError(t, err)
+		assert.Empty(t, requests)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("propagates query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, isHistory, mock.Anything).Return(nil, queryErr).Once()
+
+		requests, err := getAllReviewRequestsByReviewer(ctx, mockDB, "testuser")
+
+		assert.Nil(t, requests)
+		assert.ErrorIs(t, err, queryErr)
+	})
+}
+
+func TestEnsureSingleRow(t *testing.T) {
+	t.Run("single row passes", func(t *testing.T) {
+		res := &fakeRowsResult{rows: []string{"only"}}
+		require.True(t, res.NextRow())
+
+		assert.NoError(t, ensureSingleRow(res, "test lookup"))
+	})
+
+	t.Run("second row is reported", func(t *testing.T) {
+		res := &fakeRowsResult{rows: []string{"first", "second"}}
+		require.True(t, res.NextRow())
+
+		err := ensureSingleRow(res, "test lookup")
+
+		assert.ErrorIs(t, err, ErrMultipleRows)
+		assert.Contains(t, err.Error(), "test lookup")
+	})
+
+	t.Run("duplicate user settings rows are reported", func(t *testing.T) {
+		ctx := context.Background()
+		row := map[string]any{
+			"reviewer_login":                     "testuser",
+			"response_deadline_shift_minutes":    int32(20),
+			"non_whitelist_cancel_delay_minutes": int32(5),
+			"notify_whitelist_timeout":           true,
+			"notify_non_whitelist_cancel":        true,
+			"slot_shift_threshold_minutes":       int32(25),
+			"slot_shift_duration_minutes":        int32(15),
+			"cleanup_durations_minutes":          int32(15),
+		}
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).Return(&fakeNamedResult{rows: []map[string]any{row, row}}, nil).Once()
+
+		settings, err := getUserSettings(ctx, mockDB, "testuser")
+
+		assert.Nil(t, settings)
+		assert.ErrorIs(t, err, ErrMultipleRows)
+	})
+}
+
+func TestMatchWhitelistEntryType(t *testing.T) {
+	entries := []*models.WhitelistEntry{
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "C"},
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeProject, Name: "DO1_Linux"},
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "DevOps"},
+	}
+
+	tests := []struct {
+		name          string
+		projectName   string
+		familyLabel   string
+		expectedType  string
+		expectedMatch bool
+	}{
+		{"project only", "DO1_Linux", "Other", models.EntryTypeProject, true},
+		{"family only", "C2_SimpleBashUtils", "C", models.EntryTypeFamily, true},
+		{"both prefer project", "DO1_Linux", "DevOps", models.EntryTypeProject, true},
+		{"no match", "CPP1_s21_matrixplus", "CPP", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entryType, matched := matchWhitelistEntryType(entries, tt.projectName, tt.familyLabel)
+
+			assert.Equal(t, tt.expectedMatch, matched)
+			assert.Equal(t, tt.expectedType, entryType)
+		})
+	}
+}
+
+func TestGroupWhitelistEntries(t *testing.T) {
+	entries := []*models.WhitelistEntry{
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "C"},
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeProject, Name: "DO1_Linux"},
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeFamily, Name: "DevOps"},
+		{ReviewerLogin: "testuser", EntryType: models.EntryTypeProject, Name: "CPP1_s21_matrixplus"},
+		{ReviewerLogin: "testuser", EntryType: "UNKNOWN", Name: "ignored"},
+	}
+
+	families, projects := groupWhitelistEntries(entries)
+
+	assert.Equal(t, []string{"C", "DevOps"}, families)
+	assert.Equal(t, []string{"DO1_Linux", "CPP1_s21_matrixplus"}, projects)
+
+	families, projects = groupWhitelistEntries(nil)
+	assert.Empty(t, families)
+	assert.Empty(t, projects)
+}
+
+func TestSettingsAuditHook(t *testing.T) {
+	ctx := context.Background()
+	priorRow := map[string]any{
+		"reviewer_login":                     "testuser",
+		"response_deadline_shift_minutes":    int32(20),
+		"non_whitelist_cancel_delay_minutes": int32(5),
+		"notify_whitelist_timeout":           true,
+		"notify_non_whitelist_cancel":        true,
+		"slot_shift_threshold_minutes":       int32(25),
+		"slot_shift_duration_minutes":        int32(15),
+		"cleanup_durations_minutes":          int32(15),
+	}
+
+	type auditCall struct {
+		login, field string
+		old, new     any
+	}
+	var calls []auditCall
+	SetSettingsAuditHook(func(login, field string, old, new any) {
+		calls = append(calls, auditCall{login, field, old, new})
+	})
+	t.Cleanup(func() { SetSettingsAuditHook(nil) })
+
+	t.Run("single setting update reports old and new value", func(t *testing.T) {
+		calls = nil
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).Return(&fakeNamedResult{rows: []map[string]any{priorRow}}, nil).Once()
+		mockDB.On("Exec", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+
+		err := updateUserSetting(ctx, mockDB, "testuser", "response_deadline_shift_minutes", int32(45))
+
+		require.NoError(t, err)
+		assert.Equal(t, []auditCall{{"testuser", "response_deadline_shift_minutes", int32(20), int32(45)}}, calls)
+	})
+
+	t.Run("upsert reports only changed fields", func(t *testing.T) {
+		calls = nil
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).Return(&fakeNamedResult{rows: []map[string]any{priorRow}}, nil).Once()
+		mockDB.On("Exec", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+
+		settings := models.DefaultUserSettings("testuser")
+		settings.NotifyWhitelistTimeout = false
+
+		err := upsertUserSettings(ctx, mockDB, settings)
+
+		require.NoError(t, err)
+		assert.Equal(t, []auditCall{{"testuser", "notify_whitelist_timeout", true, false}}, calls)
+	})
+
+	t.Run("upsert of new user reports nil old values", func(t *testing.T) {
+		calls = nil
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).Return(&fakeNamedResult{}, nil).Once()
+		mockDB.On("Exec", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+
+		err := upsertUserSettings(ctx, mockDB, models.DefaultUserSettings("newuser"))
+
+		require.NoError(t, err)
+		require.Len(t, calls, 7)
+		assert.Nil(t, calls[0].old)
+		assert.Equal(t, int32(20), calls[0].new)
+	})
+
+	t.Run("failed write is not reported", func(t *testing.T) {
+		calls = nil
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).Return(&fakeNamedResult{rows: []map[string]any{priorRow}}, nil).Once()
+		mockDB.On("Exec", ctx, mock.Anything, mock.Anything).Return(errors.New("exec failed")).Once()
+
+		err := updateUserSetting(ctx, mockDB, "testuser", "cleanup_durations_minutes", int32(30))
+
+		assert.Error(t, err)
+		assert.Empty(t, calls)
+	})
+}
+
+func TestGetReviewRequestsNeedingProjectResolution(t *testing.T) {
+	ctx := context.Background()
+	unknownStatus := types.TextValue(models.StatusUnknownProjectReview).Yql()
+
+	t.Run("selects only unknown project reviews oldest first with cap", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "WHERE status = $status", "ORDER BY created_at, id", "LIMIT $limit")
+		}), mock.MatchedBy(func(params []table.ParameterOption) bool {
+			return paramValue(params, "$status") == unknownStatus &&
+				paramValue(params, "$limit") == types.Uint64Value(50).Yql()
+		})).Return(&fakeRowsResult{}, nil).Once()
+
+		requests, err := getReviewRequestsNeedingProjectResolution(ctx, mockDB, 50)
+
+		assert.NoError(t, err)
+		assert.Empty(t, requests)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("no cap omits limit", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		mockDB.On("Query", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "WHERE status = $status", "ORDER BY created_at, id") && !containsAll(sql, "$limit")
+		}), mock.MatchedBy(func(params []table.ParameterOption) bool {
+			return len(params) == 1 && paramValue(params, "$status") == unknownStatus
+		})).Return(&fakeRowsResult{}, nil).Once()
+
+		_, err := getReviewRequestsNeedingProjectResolution(ctx, mockDB, 0)
+
+		assert.NoError(t, err)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("propagates query error", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		queryErr := errors.New("query failed")
+		mockDB.On("Query", ctx, mock.Anything, mock.Anything).Return(nil, queryErr).Once()
+
+		requests, err := getReviewRequestsNeedingProjectResolution(ctx, mockDB, 10)
+
+		assert.Nil(t, requests)
+		assert.ErrorIs(t, err, queryErr)
+	})
+}
+
+func TestUpsertProjectFamilies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("large family set is written in one bulk statement", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+		families := make([]*models.ProjectFamily, 0, 600)
+		for i := 0; i < 600; i++ {
+			families = append(families, &models.ProjectFamily{
+				FamilyLabel: fmt.Sprintf("family-%d", i%12),
+				ProjectName: fmt.Sprintf("project-%d", i),
+			})
+		}
+
+		err := upsertProjectFamilies(ctx, db, families)
+
+		require.NoError(t, err)
+		require.Len(t, db.committed, 2)
+		assert.Contains(t, db.committed[0], "DELETE FROM project_families")
+		assert.True(t, containsAll(db.committed[1], "UPSERT INTO project_families", "AS_TABLE($families)"))
+
+		var written string
+		db.committedParams[1].Each(func(name string, v types.Value) {
+			if name == "$families" {
+				written = v.Yql()
+			}
+		})
+		for _, family := range families {
+			assert.Contains(t, written, `"`+family.ProjectName+`"u`)
+		}
+	})
+
+	t.Run("empty set only clears the table", func(t *testing.T) {
+		db := &fakeTxDatabase{}
+
+		err := upsertProjectFamilies(ctx, db, nil)
+
+		require.NoError(t, err)
+		require.Len(t, db.committed, 1)
+		assert.Contains(t, db.committed[0], "DELETE FROM project_families")
+	})
+
+	t.Run("failed upsert rolls back the clear", func(t *testing.T) {
+		db := &fakeTxDatabase{failOn: "UPSERT INTO project_families"}
+
+		err := upsertProjectFamilies(ctx, db, []*models.ProjectFamily{{FamilyLabel: "C", ProjectName: "C2_SimpleBashUtils"}})
+
+		assert.Error(t, err)
+		assert.Empty(t, db.committed)
+	})
+}
+
+func TestUpdateReviewRequestToWaitingForApprove_DeadlineMinimum(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("past deadline is rejected", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		past := uint32(time.Now().Add(-time.Minute).Unix())
+
+		err := updateReviewRequestToWaitingForApprove(ctx, mockDB, "req-1", past, "42")
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "decision deadline")
+		mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("deadline inside the minimum lead is rejected", func(t *testing.T) {
+		SetMinDecisionDeadlineLead(time.Minute)
+		t.Cleanup(func() { SetMinDecisionDeadlineLead(DefaultMinDecisionDeadlineLead) })
+		mockDB := NewMockDatabase()
+		soon := uint32(time.Now().Add(30 * time.Second).Unix())
+
+		err := updateReviewRequestToWaitingForApprove(ctx, mockDB, "req-1", soon, "42")
+
+		assert.Error(t, err)
+		mockDB.AssertNotCalled(t, "Exec", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("future deadline is accepted", func(t *testing.T) {
+		mockDB := NewMockDatabase()
+		future := uint32(time.Now().Add(20 * time.Minute).Unix())
+		mockDB.On("Exec", ctx, mock.MatchedBy(func(sql string) bool {
+			return containsAll(sql, "UPDATE review_requests", "SET decision_deadline = $decision_deadline")
+		}), mock.MatchedBy(func(params []table.ParameterOption) bool {
+			return paramValue(params, "$status") == types.TextValue(models.StatusWaitingForApprove).Yql()
+		})).Return(nil).Once()
+
+		err := updateReviewRequestToWaitingForApprove(ctx, mockDB, "req-1", future, "42")
+
+		assert.NoError(t, err)
+		mockDB.AssertExpectations(t)
+	})
+}